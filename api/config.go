@@ -3,6 +3,7 @@ package api
 import (
 	"github.com/mrod502/logger"
 	"github.com/mrod502/stockscraper/db"
+	"github.com/mrod502/stockscraper/enrich"
 	"github.com/mrod502/stockscraper/obj"
 	"github.com/mrod502/stockscraper/scraper"
 )
@@ -11,6 +12,12 @@ type Config struct {
 	Obj       obj.Config
 	Scraper   scraper.Config
 	Db        db.Config
+	Enrich    enrich.Config
 	ServePort uint16
 	Logger    logger.ClientConfig
+	MaxCrawls int // crawls allowed to run at once, defaults to defaultMaxCrawls
+
+	MaxHostRate    float64 // highest HostRate a crawl may ask for, defaults to scraper.DefaultHostRate
+	MaxHostBurst   int     // highest HostBurst a crawl may ask for, defaults to scraper.DefaultHostBurst
+	CrawlUserAgent string  // user agent of every crawl, defaults to scraper.DefaultUserAgent
 }
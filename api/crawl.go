@@ -0,0 +1,299 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/mrod502/stockscraper/obj"
+	"github.com/mrod502/stockscraper/scraper"
+)
+
+const (
+	crawlWorkers     = 8
+	defaultMaxCrawls = 4
+)
+
+var (
+	ErrTooManyCrawls  = errors.New("too many crawls running")
+	ErrCrawlRunning   = errors.New("crawl is already running")
+	ErrCrawlNotActive = errors.New("crawl is not running")
+	ErrCrawlParams    = errors.New("invalid crawl parameters")
+)
+
+type runningCrawl struct {
+	c      *scraper.Crawler
+	cancel context.CancelFunc
+}
+
+// crawl starts a crawl job in the background and responds with the job. The
+// body is a scraper.CrawlerParams.
+func (s *Server) crawl(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.err("crawl", r.RemoteAddr, err.Error())
+		return
+	}
+
+	var params scraper.CrawlerParams
+	err = json.Unmarshal(b, &params)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.err("crawl", r.RemoteAddr, err.Error())
+		return
+	}
+	params.Job = uuid.New().String()
+
+	if err = s.startCrawl(params); err != nil {
+		s.crawlError(w, err)
+		s.err("crawl", r.RemoteAddr, err.Error())
+		return
+	}
+	s.writeCrawlJob(w, params.Job, http.StatusAccepted)
+}
+
+func (s *Server) crawlJobs(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	jobs, err := scraper.CrawlJobs(s.db)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.err("crawl", "jobs", err.Error())
+		return
+	}
+	for i := range jobs {
+		s.liveStats(&jobs[i])
+	}
+	b, _ := json.Marshal(jobs)
+	if _, err = w.Write(b); err != nil {
+		s.err("crawl", r.RemoteAddr, err.Error())
+	}
+}
+
+// crawlJob reports the status and progress of a crawl.
+func (s *Server) crawlJob(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	s.writeCrawlJob(w, mux.Vars(r)["id"], http.StatusOK)
+}
+
+// cancelCrawl stops a running crawl and drops its frontier.
+func (s *Server) cancelCrawl(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	id := mux.Vars(r)["id"]
+	rc, ok := s.runningCrawl(id)
+	if !ok {
+		s.crawlError(w, ErrCrawlNotActive)
+		return
+	}
+	rc.cancel()
+	s.log("crawl", "cancel", id)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// pauseCrawl stops a running crawl. Its frontier is kept so it can be resumed.
+func (s *Server) pauseCrawl(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	id := mux.Vars(r)["id"]
+	rc, ok := s.runningCrawl(id)
+	if !ok {
+		s.crawlError(w, ErrCrawlNotActive)
+		return
+	}
+	rc.c.Pause()
+	s.log("crawl", "pause", id)
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// resumeCrawl restarts a paused or interrupted crawl from its frontier.
+func (s *Server) resumeCrawl(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	job, err := scraper.LoadCrawlJob(s.db, mux.Vars(r)["id"])
+	if err == nil && job.Status != scraper.CrawlRunning && job.Status != scraper.CrawlPaused {
+		err = ErrCrawlNotActive
+	}
+	if err == nil {
+		err = s.startCrawl(job.Params)
+	}
+	if err != nil {
+		s.crawlError(w, err)
+		return
+	}
+	s.writeCrawlJob(w, job.Id, http.StatusAccepted)
+}
+
+// resumeCrawls restarts the crawls that were running when the server stopped.
+func (s *Server) resumeCrawls() {
+	jobs, err := scraper.CrawlJobs(s.db)
+	if err != nil {
+		s.err("crawl", "jobs", err.Error())
+		return
+	}
+	for _, job := range jobs {
+		if job.Status != scraper.CrawlRunning {
+			continue
+		}
+		if err = s.startCrawl(job.Params); err != nil {
+			s.err("crawl", "resume", job.Id, err.Error())
+		}
+	}
+}
+
+// crawlParams checks the per host rate a caller asked for against the
+// server's limits and sets the user agent crawls identify themselves with.
+func (s *Server) crawlParams(params *scraper.CrawlerParams) error {
+	maxRate, maxBurst := s.c.MaxHostRate, s.c.MaxHostBurst
+	if maxRate <= 0 {
+		maxRate = scraper.DefaultHostRate
+	}
+	if maxBurst <= 0 {
+		maxBurst = scraper.DefaultHostBurst
+	}
+	if params.HostRate < 0 || params.HostRate > maxRate {
+		return fmt.Errorf("%w: HostRate must be at most %g", ErrCrawlParams, maxRate)
+	}
+	if params.HostBurst < 0 || params.HostBurst > maxBurst {
+		return fmt.Errorf("%w: HostBurst must be at most %d", ErrCrawlParams, maxBurst)
+	}
+	// the crawler's default rate may be above a lower configured limit
+	if params.HostRate == 0 {
+		params.HostRate = math.Min(scraper.DefaultHostRate, maxRate)
+	}
+	params.UserAgent = s.c.CrawlUserAgent
+	return nil
+}
+
+// startCrawl runs the crawl for params.Job in the background.
+func (s *Server) startCrawl(params scraper.CrawlerParams) error {
+	if err := s.crawlParams(&params); err != nil {
+		return err
+	}
+	c, err := scraper.NewCrawler(params)
+	if err != nil {
+		return err
+	}
+
+	c.SetApplicationFileHandler(func(r *http.Response) error {
+		if r.Header.Get("content-type") == "application/pdf" {
+			s.newDocsChan <- newCrawledDocument(r)
+		}
+		return nil
+	})
+
+	c.SetTextFileHandler(func(r *http.Response) error {
+		s.newDocsChan <- newCrawledDocument(r)
+		return nil
+	})
+	c.SetFrontier(scraper.NewFrontier(s.db, params))
+	c.SetLogger(s.l)
+
+	max := s.c.MaxCrawls
+	if max <= 0 {
+		max = defaultMaxCrawls
+	}
+	s.crawlsL.Lock()
+	defer s.crawlsL.Unlock()
+	if _, running := s.crawls[params.Job]; running {
+		return ErrCrawlRunning
+	}
+	if len(s.crawls) >= max {
+		return ErrTooManyCrawls
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.crawls[params.Job] = &runningCrawl{c: c, cancel: cancel}
+	s.crawlsWg.Add(1)
+	go func() {
+		defer s.crawlsWg.Done()
+		defer cancel()
+		s.log("crawling", params.Job)
+		if err := c.Crawl(ctx, crawlWorkers); err != nil {
+			s.err("crawl", params.Job, err.Error())
+		}
+		s.crawlsL.Lock()
+		delete(s.crawls, params.Job)
+		s.crawlsL.Unlock()
+		s.log("crawl", "stopped", params.Job)
+	}()
+	return nil
+}
+
+// stopCrawls interrupts every running crawl, leaving them to be resumed by the
+// next Serve, and waits for them to stop.
+func (s *Server) stopCrawls() {
+	s.crawlsL.Lock()
+	for _, rc := range s.crawls {
+		rc.c.Interrupt()
+	}
+	s.crawlsL.Unlock()
+	s.crawlsWg.Wait()
+}
+
+func (s *Server) runningCrawl(id string) (*runningCrawl, bool) {
+	s.crawlsL.Lock()
+	defer s.crawlsL.Unlock()
+	rc, ok := s.crawls[id]
+	return rc, ok
+}
+
+// liveStats replaces the stored progress of a running job with the crawler's.
+func (s *Server) liveStats(job *scraper.CrawlJob) {
+	if rc, ok := s.runningCrawl(job.Id); ok {
+		job.Status = scraper.CrawlRunning
+		job.CrawlStats = rc.c.Stats()
+	}
+}
+
+func (s *Server) writeCrawlJob(w http.ResponseWriter, id string, status int) {
+	job, err := scraper.LoadCrawlJob(s.db, id)
+	if errors.Is(err, scraper.ErrCrawlJobNotFound) {
+		if _, ok := s.runningCrawl(id); ok {
+			// the crawl goroutine has not stored it yet
+			job, err = scraper.CrawlJob{Id: id}, nil
+		}
+	}
+	if err != nil {
+		s.crawlError(w, err)
+		return
+	}
+	s.liveStats(&job)
+	b, _ := json.Marshal(job)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+	if _, err = w.Write(b); err != nil {
+		s.err("crawl", id, err.Error())
+	}
+}
+
+func (s *Server) crawlError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, scraper.ErrCrawlJobNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrCrawlParams):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrTooManyCrawls):
+		http.Error(w, err.Error(), http.StatusTooManyRequests)
+	case errors.Is(err, ErrCrawlRunning), errors.Is(err, ErrCrawlNotActive):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.err("crawl", err.Error())
+	}
+}
+
+// newCrawledDocument keeps the text of the link the crawler followed. It
+// becomes the title if none is found in the content, so the classifiers have
+// something to go on besides the URL.
+func newCrawledDocument(r *http.Response) *obj.Document {
+	doc := obj.NewDocument(r)
+	if l, ok := scraper.LinkFromResponse(r); ok {
+		doc.LinkText = l.Text
+	}
+	return doc
+}
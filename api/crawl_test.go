@@ -0,0 +1,34 @@
+package api
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mrod502/stockscraper/scraper"
+)
+
+func TestCrawlParams(t *testing.T) {
+	s := &Server{c: Config{MaxHostRate: 0.5, MaxHostBurst: 2, CrawlUserAgent: "acme-research/2.0"}}
+	for _, c := range []struct {
+		rate  float64
+		burst int
+		ok    bool
+	}{
+		{0, 0, true},
+		{0.5, 2, true},
+		{1000, 2, false},
+		{0.5, 10, false},
+		{-1, 0, false},
+		{0, -1, false},
+	} {
+		params := scraper.CrawlerParams{HostRate: c.rate, HostBurst: c.burst, UserAgent: "Googlebot/2.1"}
+		err := s.crawlParams(&params)
+		if c.ok != (err == nil) || err != nil && !errors.Is(err, ErrCrawlParams) {
+			t.Errorf("rate %g burst %d: %v", c.rate, c.burst, err)
+			continue
+		}
+		if err == nil && (params.HostRate <= 0 || params.HostRate > 0.5 || params.UserAgent != "acme-research/2.0") {
+			t.Errorf("rate %g burst %d: params = %+v", c.rate, c.burst, params)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/gorilla/mux"
+	"github.com/mrod502/stockscraper/obj"
+)
+
+const defaultDiffContext = 3
+
+type RefreshResult struct {
+	Changed  bool
+	Document *obj.Document
+}
+
+// DocumentResult is a stored document with the sources of every document
+// whose current copy has the same content.
+type DocumentResult struct {
+	Document *obj.Document
+	Mirrors  []string
+}
+
+// Document responds with a stored document and the URLs it is mirrored at.
+func (s *Server) Document(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	doc, ok := s.loadDocument(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	ids, err := doc.Mirrors()
+	if err != nil {
+		s.err("document", doc.Id, err.Error())
+	}
+	mirrors := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if id == doc.Id {
+			mirrors = append(mirrors, doc.Source)
+			continue
+		}
+		var m obj.Document
+		if err := s.db.Get(id, &m); err != nil || m.Blob != doc.Blob {
+			// gone, or only an earlier version had this content
+			continue
+		}
+		mirrors = append(mirrors, m.Source)
+	}
+	b, _ := json.Marshal(DocumentResult{Document: doc, Mirrors: mirrors})
+	if _, err = w.Write(b); err != nil {
+		s.err("document", r.RemoteAddr, err.Error())
+	}
+}
+
+// Refresh checks a stored document's source for a newer copy using the
+// validators from its last fetch, and stores it as a new version if the
+// content changed.
+func (s *Server) Refresh(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+
+	id := mux.Vars(r)["id"]
+	doc, ok := s.loadDocument(w, id)
+	if !ok {
+		return
+	}
+
+	changed, err := doc.Refresh(r.Context())
+	// a changed document has a new version and blob even if its text could
+	// not be extracted, so it is stored here as well as by the pipeline
+	if changed || err == nil {
+		if perr := s.db.Put(doc.Id, doc); perr != nil {
+			s.err("refresh", id, perr.Error())
+		}
+	}
+	if err != nil {
+		status := http.StatusBadGateway
+		if changed {
+			status = http.StatusInternalServerError
+		}
+		http.Error(w, err.Error(), status)
+		s.err("refresh", id, err.Error())
+		return
+	}
+	s.log("refresh", id, doc.Source)
+
+	b, _ := json.Marshal(RefreshResult{Changed: changed, Document: doc})
+	if _, err = w.Write(b); err != nil {
+		s.err("refresh", r.RemoteAddr, err.Error())
+	}
+}
+
+// Versions lists the stored versions of a document, oldest first. The last
+// one is the current copy.
+func (s *Server) Versions(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	doc, ok := s.loadDocument(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	b, _ := json.Marshal(doc.History())
+	if _, err := w.Write(b); err != nil {
+		s.err("versions", r.RemoteAddr, err.Error())
+	}
+}
+
+// Version serves the content of one version of a document.
+func (s *Server) Version(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	doc, ok := s.loadDocument(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(mux.Vars(r)["n"])
+	if err != nil {
+		http.Error(w, "invalid version", http.StatusBadRequest)
+		return
+	}
+	b, _, err := doc.LoadVersion(n)
+	if err != nil {
+		s.versionError(w, doc.Id, err)
+		return
+	}
+	w.Header().Set("content-type", doc.History()[n-1].ContentType)
+	if _, err = w.Write(b); err != nil {
+		s.err("version", r.RemoteAddr, err.Error())
+	}
+}
+
+// VersionDiff responds with a unified diff of the extracted text of version n
+// against an earlier version. Parameters: from (defaults to n-1) and context
+// (lines around each change, defaults to 3).
+func (s *Server) VersionDiff(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	doc, ok := s.loadDocument(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	n, err := strconv.Atoi(mux.Vars(r)["n"])
+	if err != nil {
+		http.Error(w, "invalid version", http.StatusBadRequest)
+		return
+	}
+	from, lines := n-1, defaultDiffContext
+	for param, v := range map[string]*int{"from": &from, "context": &lines} {
+		if q := r.URL.Query().Get(param); q != "" {
+			if *v, err = strconv.Atoi(q); err != nil || *v < 0 {
+				http.Error(w, "invalid "+param, http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	_, oldText, err := doc.LoadVersion(from)
+	if err != nil {
+		s.versionError(w, doc.Id, err)
+		return
+	}
+	_, newText, err := doc.LoadVersion(n)
+	if err != nil {
+		s.versionError(w, doc.Id, err)
+		return
+	}
+	diff := obj.UnifiedDiff(fmt.Sprintf("%s v%d", doc.Id, from), fmt.Sprintf("%s v%d", doc.Id, n), oldText, newText, lines)
+	w.Header().Set("content-type", "text/plain; charset=utf-8")
+	if _, err = w.Write([]byte(diff)); err != nil {
+		s.err("diff", r.RemoteAddr, err.Error())
+	}
+}
+
+// Tables responds with the tables found in a document. With format=csv
+// every table is written as CSV, separated by blank lines.
+func (s *Server) Tables(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	doc, ok := s.loadDocument(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	tables, err := doc.LoadTables()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.err("tables", doc.Id, err.Error())
+		return
+	}
+	s.writeTables(w, r, tables)
+}
+
+// Table responds with table n of a document, numbered from 1, as JSON or,
+// with format=csv, as CSV.
+func (s *Server) Table(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	doc, ok := s.loadDocument(w, mux.Vars(r)["id"])
+	if !ok {
+		return
+	}
+	tables, err := doc.LoadTables()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.err("tables", doc.Id, err.Error())
+		return
+	}
+	n, err := strconv.Atoi(mux.Vars(r)["n"])
+	if err != nil || n < 1 || n > len(tables) {
+		http.Error(w, "no such table", http.StatusNotFound)
+		return
+	}
+	if r.URL.Query().Get("format") == "csv" {
+		s.writeTables(w, r, tables[n-1:n])
+		return
+	}
+	b, _ := json.Marshal(tables[n-1])
+	if _, err = w.Write(b); err != nil {
+		s.err("tables", r.RemoteAddr, err.Error())
+	}
+}
+
+func (s *Server) writeTables(w http.ResponseWriter, r *http.Request, tables []obj.Table) {
+	var err error
+	if r.URL.Query().Get("format") != "csv" {
+		b, _ := json.Marshal(tables)
+		_, err = w.Write(b)
+	} else {
+		w.Header().Set("content-type", "text/csv; charset=utf-8")
+		for i, t := range tables {
+			if i > 0 {
+				if _, err = w.Write([]byte("\n")); err != nil {
+					break
+				}
+			}
+			if err = t.WriteCSV(w); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		s.err("tables", r.RemoteAddr, err.Error())
+	}
+}
+
+// loadDocument reads document id from the db, responding with an error if it
+// could not.
+func (s *Server) loadDocument(w http.ResponseWriter, id string) (*obj.Document, bool) {
+	var doc = new(obj.Document)
+	if err := s.db.Get(id, doc); err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			http.Error(w, "document not found", http.StatusNotFound)
+			return nil, false
+		}
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.err("document", id, err.Error())
+		return nil, false
+	}
+	return doc, true
+}
+
+func (s *Server) versionError(w http.ResponseWriter, id string, err error) {
+	if errors.Is(err, obj.ErrNoVersion) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+	s.err("version", id, err.Error())
+}
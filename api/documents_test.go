@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/gorilla/mux"
+	"github.com/mrod502/stockscraper/db"
+	"github.com/mrod502/stockscraper/obj"
+	"github.com/mrod502/stockscraper/search"
+	"github.com/mrod502/stockscraper/xbrl"
+)
+
+func TestRefreshUnsupported(t *testing.T) {
+	d, err := db.New(db.Config{BadgerOpts: badger.DefaultOptions("").WithInMemory(true).WithLogger(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	s := &Server{db: d, idx: search.NewIndex(d), xbrl: xbrl.NewStore(d), l: nopLogger{}}
+
+	ct, content := "text/plain", "quarterly update"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", ct)
+		w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	obj.Setup(obj.Config{FileStorePath: t.TempDir()})
+	doc := &obj.Document{Item: obj.NewItem(obj.TDocument), Source: srv.URL + "/update"}
+	if err = doc.Create(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = obj.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Put(doc.Id, doc); err != nil {
+		t.Fatal(err)
+	}
+
+	// the new copy is stored but its text can't be extracted
+	ct, content = "application/zip", "PK\x03\x04"
+	w := httptest.NewRecorder()
+	req := mux.SetURLVars(httptest.NewRequest(http.MethodPost, "/documents/"+doc.Id+"/refresh", nil), map[string]string{"id": doc.Id})
+	s.Refresh(w, req)
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("refresh = %d %s", w.Code, w.Body)
+	}
+	var stored obj.Document
+	if err = d.Get(doc.Id, &stored); err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.Versions) != 1 || stored.Hash != obj.GetSignature([]byte(content)) || stored.ContentType != ct {
+		t.Fatalf("stored = %+v", stored)
+	}
+}
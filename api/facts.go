@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/mrod502/stockscraper/enrich"
+	"github.com/mrod502/stockscraper/obj"
+)
+
+// Facts are stored one key per fact under their symbol, so all the research
+// on a symbol can be read with a single scan.
+const prefixFact = "fact:"
+
+type FactsResult struct {
+	Facts     []obj.Fact
+	Consensus []enrich.Consensus
+}
+
+func factKey(f obj.Fact, i int) string {
+	return fmt.Sprintf("%s%s:%s:%d", prefixFact, f.Symbol, f.Document, i)
+}
+
+// storeFacts replaces the stored facts of d with d.Facts.
+func (s *Server) storeFacts(d *obj.Document) error {
+	var prev obj.Document
+	err := s.db.Get(d.Id, &prev)
+	if err != nil && !errors.Is(err, badger.ErrKeyNotFound) {
+		return err
+	}
+	if len(prev.Facts) > 0 {
+		keys := make([]string, 0, len(prev.Facts))
+		for i, f := range prev.Facts {
+			keys = append(keys, factKey(f, i))
+		}
+		if err = s.db.DeleteMany(keys); err != nil {
+			return err
+		}
+	}
+	if len(d.Facts) == 0 {
+		return nil
+	}
+	kv := make(map[string]any, len(d.Facts))
+	for i, f := range d.Facts {
+		kv[factKey(f, i)] = f
+	}
+	return s.db.PutMany(kv)
+}
+
+// Facts responds with the facts stored about a symbol, oldest first, and
+// their consensus. Parameters: symbol (required), kind, period and since
+// (a date, 2006-01-02).
+func (s *Server) Facts(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	q := r.URL.Query()
+	symbol := strings.ToUpper(strings.TrimSpace(q.Get("symbol")))
+	if symbol == "" {
+		http.Error(w, "symbol is required", http.StatusBadRequest)
+		return
+	}
+	var since time.Time
+	if v := q.Get("since"); v != "" {
+		var err error
+		if since, err = time.Parse("2006-01-02", v); err != nil {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return
+		}
+	}
+	kind, period := q.Get("kind"), strings.ToUpper(q.Get("period"))
+
+	facts := make([]obj.Fact, 0)
+	err := s.db.Scan(prefixFact+symbol+":", func(key string, decode func(v interface{}) error) error {
+		var f obj.Fact
+		if err := decode(&f); err != nil {
+			return err
+		}
+		if kind != "" && f.Kind != kind || period != "" && f.Period != period || f.Date.Before(since) {
+			return nil
+		}
+		facts = append(facts, f)
+		return nil
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.err("facts", symbol, err.Error())
+		return
+	}
+	sort.SliceStable(facts, func(i, j int) bool { return facts[i].Date.Before(facts[j].Date) })
+
+	b, _ := json.Marshal(FactsResult{Facts: facts, Consensus: enrich.Summarize(facts)})
+	if _, err = w.Write(b); err != nil {
+		s.err("facts", r.RemoteAddr, err.Error())
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/mrod502/stockscraper/obj"
+	"github.com/mrod502/stockscraper/xbrl"
+)
+
+// Concepts without a prefix are taken to be US GAAP.
+const defaultConceptPrefix = "us-gaap:"
+
+type FundamentalsResult struct {
+	Symbol   string
+	Concept  string             `json:",omitempty"`
+	Series   []xbrl.Observation `json:",omitempty"`
+	Concepts []string           `json:",omitempty"`
+}
+
+// mayBeXBRL reports whether d is of a type XBRL comes in: an XML instance or
+// an inline XBRL (XHTML) filing.
+func mayBeXBRL(d *obj.Document) bool {
+	ct, _, _ := mime.ParseMediaType(d.ContentType)
+	if ct == "" {
+		switch strings.ToLower(path.Ext(d.Source)) {
+		case ".xml", ".htm", ".html", ".xhtml":
+			return true
+		}
+	}
+	return strings.Contains(ct, "xml") || strings.Contains(ct, "html")
+}
+
+// ingestXBRL stores the tagged facts of XBRL and inline XBRL filings. A
+// filing that cannot be parsed is logged and otherwise saved as usual.
+func (s *Server) ingestXBRL(d *obj.Document, text string) error {
+	if !mayBeXBRL(d) {
+		return nil
+	}
+	b, err := d.Content()
+	if err != nil {
+		s.err("xbrl", d.Id, err.Error())
+		return nil
+	}
+	if !xbrl.Sniff(b) {
+		return nil
+	}
+	inst, err := xbrl.Parse(b)
+	if err != nil {
+		s.err("xbrl", d.Id, err.Error())
+		return nil
+	}
+	symbol := strings.ToUpper(inst.Text("dei:TradingSymbol"))
+	switch {
+	case symbol != "" && !contains(d.Symbols, symbol):
+		d.Symbols = append(d.Symbols, symbol)
+	case symbol == "" && len(d.Symbols) > 0:
+		symbol = d.Symbols[0]
+	case symbol == "":
+		s.err("xbrl", d.Id, "no trading symbol")
+		return nil
+	}
+	filed := d.PostedDate
+	if filed.IsZero() {
+		filed = d.Fetched
+	}
+	n, err := s.xbrl.Put(symbol, d.Id, inst.Text("dei:DocumentType"), filed, inst)
+	if err != nil {
+		return err
+	}
+	s.log("xbrl", d.Id, symbol, strconv.Itoa(n), "facts")
+	return nil
+}
+
+// Fundamentals responds with the time series of an XBRL concept reported by
+// a symbol's filings, e.g. ?symbol=AAPL&concept=Revenues&duration=quarter.
+// Without a concept it lists the concepts stored for the symbol. Optional
+// parameters: duration (quarter, year or instant) and dimensions=true to
+// include segment figures.
+func (s *Server) Fundamentals(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	q := r.URL.Query()
+	symbol := strings.ToUpper(strings.TrimSpace(q.Get("symbol")))
+	if symbol == "" {
+		http.Error(w, "symbol is required", http.StatusBadRequest)
+		return
+	}
+	res := FundamentalsResult{Symbol: symbol, Concept: strings.TrimSpace(q.Get("concept"))}
+	if res.Concept == "" {
+		res.Concepts = s.xbrl.Concepts(symbol)
+	} else {
+		if !strings.Contains(res.Concept, ":") {
+			res.Concept = defaultConceptPrefix + res.Concept
+		}
+		sq := xbrl.SeriesQuery{Duration: q.Get("duration"), Dimensions: q.Get("dimensions") == "true"}
+		switch sq.Duration {
+		case "", xbrl.DurationQuarter, xbrl.DurationYear, xbrl.DurationInstant:
+		default:
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+		var err error
+		if res.Series, err = s.xbrl.Series(symbol, res.Concept, sq); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			s.err("fundamentals", symbol, err.Error())
+			return
+		}
+	}
+	b, _ := json.Marshal(res)
+	if _, err := w.Write(b); err != nil {
+		s.err("fundamentals", r.RemoteAddr, err.Error())
+	}
+}
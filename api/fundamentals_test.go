@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/mrod502/logger"
+	"github.com/mrod502/stockscraper/db"
+	"github.com/mrod502/stockscraper/obj"
+	"github.com/mrod502/stockscraper/search"
+	"github.com/mrod502/stockscraper/xbrl"
+)
+
+const testInstance = `<?xml version="1.0" encoding="utf-8"?>
+<xbrli:xbrl xmlns:xbrli="http://www.xbrl.org/2003/instance" xmlns:us-gaap="http://fasb.org/us-gaap/2023"
+  xmlns:dei="http://xbrl.sec.gov/dei/2023" xmlns:iso4217="http://www.xbrl.org/2003/iso4217">
+  <xbrli:context id="q4">
+    <xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">0000320193</xbrli:identifier></xbrli:entity>
+    <xbrli:period><xbrli:startDate>2023-07-02</xbrli:startDate><xbrli:endDate>2023-09-30</xbrli:endDate></xbrli:period>
+  </xbrli:context>
+  <xbrli:unit id="usd"><xbrli:measure>iso4217:USD</xbrli:measure></xbrli:unit>
+  <dei:TradingSymbol contextRef="q4">AAPL</dei:TradingSymbol>
+  <dei:DocumentType contextRef="q4">10-K</dei:DocumentType>
+  <us-gaap:Revenues contextRef="q4" unitRef="usd" decimals="-6">89498000000</us-gaap:Revenues>
+</xbrli:xbrl>`
+
+// nopLogger drops everything written to it.
+type nopLogger struct{ logger.Client }
+
+func (nopLogger) Write(...string) error { return nil }
+
+func TestFundamentalsPipeline(t *testing.T) {
+	d, err := db.New(db.Config{BadgerOpts: badger.DefaultOptions("").WithInMemory(true).WithLogger(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	s := &Server{db: d, idx: search.NewIndex(d), xbrl: xbrl.NewStore(d), l: nopLogger{}}
+	obj.AddStage(obj.StageFunc(s.ingestXBRL))
+	obj.AddStage(obj.StageFunc(s.persist))
+
+	for _, ct := range []string{"application/xml", "application/xbrl+xml"} {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("content-type", ct)
+			w.Write([]byte(testInstance))
+		}))
+
+		obj.Setup(obj.Config{FileStorePath: t.TempDir()})
+		doc := &obj.Document{Item: obj.NewItem(obj.TDocument), Source: srv.URL + "/aapl-20230930_htm.xml"}
+		if err = doc.Create(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = obj.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		srv.Close()
+
+		var stored obj.Document
+		if err = d.Get(doc.Id, &stored); err != nil {
+			t.Fatalf("%s: document not stored: %v", ct, err)
+		}
+		if !contains(stored.Symbols, "AAPL") {
+			t.Fatalf("%s: symbols = %v", ct, stored.Symbols)
+		}
+
+		w := httptest.NewRecorder()
+		s.Fundamentals(w, httptest.NewRequest(http.MethodGet, "/fundamentals?symbol=aapl&concept=Revenues", nil))
+		var res FundamentalsResult
+		if err = json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("%s: %v: %s", ct, err, w.Body)
+		}
+		if len(res.Series) != 1 || res.Series[0].Value != 89498000000 {
+			t.Fatalf("%s: series = %+v", ct, res.Series)
+		}
+	}
+}
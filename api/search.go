@@ -0,0 +1,122 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/mrod502/stockscraper/obj"
+	"github.com/mrod502/stockscraper/search"
+)
+
+const (
+	defaultSearchLimit = 25
+	reindexBatch       = 256
+)
+
+type SearchResult struct {
+	Document *obj.Document
+	Score    float64
+	Snippet  string
+}
+
+// Search runs a ranked full-text query over extracted document text.
+// Parameters: q (required) and limit.
+func (s *Server) Search(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+
+	q := r.URL.Query().Get("q")
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	s.log("search", r.RemoteAddr, q)
+
+	hits, err := s.idx.Search(q, limit)
+	if err == search.ErrEmptyQuery {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		s.err("search", r.RemoteAddr, q, err.Error())
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res := make([]SearchResult, 0, len(hits))
+	for _, h := range hits {
+		var doc = new(obj.Document)
+		if err := s.db.Get(h.Id, doc); err != nil {
+			s.err("search", "load", h.Id, err.Error())
+			continue
+		}
+		var snippet string
+		if text, err := doc.Text(); err == nil {
+			snippet = search.Snippet(text, q)
+		}
+		res = append(res, SearchResult{Document: doc, Score: h.Score, Snippet: snippet})
+	}
+
+	b, _ := json.Marshal(res)
+	if _, err = w.Write(b); err != nil {
+		s.err("search", r.RemoteAddr, err.Error())
+	}
+}
+
+type ReindexResult struct {
+	Documents int
+}
+
+// Reindex rebuilds the search index from the extracted text files of every
+// stored document, including those saved before the index existed. Documents
+// are read with a scan and indexed reindexBatch at a time rather than loaded
+// all at once.
+func (s *Server) Reindex(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	var res ReindexResult
+	err := s.idx.Rebuild(func(add func(id, text string) error) error {
+		batch := make([]*obj.Document, 0, reindexBatch)
+		flush := func() error {
+			for _, d := range batch {
+				text, err := d.Text()
+				if err != nil || text == "" {
+					continue // nothing was extracted
+				}
+				if err = add(d.Id, text); err != nil {
+					return err
+				}
+				res.Documents++
+			}
+			batch = batch[:0]
+			return nil
+		}
+		err := s.db.Scan("", func(key string, decode func(v interface{}) error) error {
+			if strings.Contains(key, ":") {
+				return nil // namespaced records such as the index itself
+			}
+			d := new(obj.Document)
+			if err := decode(d); err != nil || d.Item == nil || d.Class != obj.TDocument {
+				return nil
+			}
+			if batch = append(batch, d); len(batch) < reindexBatch {
+				return nil
+			}
+			return flush()
+		})
+		if err != nil {
+			return err
+		}
+		return flush()
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.err("reindex", err.Error())
+		return
+	}
+	s.log("reindex", strconv.Itoa(res.Documents), "documents")
+	b, _ := json.Marshal(res)
+	if _, err = w.Write(b); err != nil {
+		s.err("reindex", r.RemoteAddr, err.Error())
+	}
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	gocache "github.com/mrod502/go-cache"
+	"github.com/mrod502/stockscraper/enrich"
+	"github.com/mrod502/stockscraper/obj"
+)
+
+const maxToneDocuments = 10000
+
+// toneQuery matches the scored documents about a symbol, optionally of one
+// type.
+type toneQuery struct {
+	symbol, typ string
+}
+
+func (q toneQuery) Match(v gocache.Object) bool {
+	d := v.(*obj.Document)
+	return d.Sentiment != nil && contains(d.Symbols, q.symbol) && (q.typ == "" || strings.EqualFold(d.Type, q.typ))
+}
+
+func (q toneQuery) GetLimit() uint { return maxToneDocuments }
+
+// Sentiment responds with the tone of the documents about a symbol, oldest
+// first, each with its change since the previous document of the same type.
+// Parameters: symbol (required) and type, e.g. "10-K".
+func (s *Server) Sentiment(w http.ResponseWriter, r *http.Request) {
+	enableCors(w)
+	q := r.URL.Query()
+	symbol := strings.ToUpper(strings.TrimSpace(q.Get("symbol")))
+	if symbol == "" {
+		http.Error(w, "symbol is required", http.StatusBadRequest)
+		return
+	}
+	res, err := s.db.Where(toneQuery{symbol: symbol, typ: q.Get("type")})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.err("sentiment", symbol, err.Error())
+		return
+	}
+	docs := make([]*obj.Document, 0, len(res))
+	for _, v := range res {
+		docs = append(docs, v.(*obj.Document))
+	}
+	b, _ := json.Marshal(enrich.ToneSeries(docs))
+	if _, err = w.Write(b); err != nil {
+		s.err("sentiment", r.RemoteAddr, err.Error())
+	}
+}
@@ -1,31 +1,52 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"math"
+	"net"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
 	gocache "github.com/mrod502/go-cache"
 	"github.com/mrod502/logger"
 	"github.com/mrod502/stockscraper/db"
+	"github.com/mrod502/stockscraper/enrich"
 	"github.com/mrod502/stockscraper/obj"
 	"github.com/mrod502/stockscraper/scraper"
-	"go.uber.org/atomic"
+	"github.com/mrod502/stockscraper/search"
+	"github.com/mrod502/stockscraper/xbrl"
+)
+
+const (
+	prefixPendingDoc = "pending:doc:"
+	shutdownTimeout  = 30 * time.Second
 )
 
 type Server struct {
 	router      *mux.Router
 	db          *db.DB
+	idx         *search.Index
+	ref         *enrich.Reference
+	xbrl        *xbrl.Store
+	tax         *enrich.Taxonomy
+	lex         *enrich.Lexicon
 	v           *gocache.Cache[interface{}, string]
 	l           logger.Client
 	s           scraper.Client
 	c           Config
 	newDocsChan chan *obj.Document
-	crawlReset  *atomic.Int64
+	crawls      map[string]*runningCrawl // by job id
+	crawlsL     *sync.Mutex
+	crawlsWg    *sync.WaitGroup
 }
 
 func NewServer(cfg Config, errHandler func(error)) (s *Server, err error) {
@@ -41,11 +62,14 @@ func NewServer(cfg Config, errHandler func(error)) (s *Server, err error) {
 		router:      mux.NewRouter(),
 		v:           gocache.New[interface{}, string](),
 		db:          db,
+		idx:         search.NewIndex(db),
+		xbrl:        xbrl.NewStore(db),
 		newDocsChan: make(chan *obj.Document, 512),
 		l:           l,
 		c:           cfg,
-		s:           scraper.NewGoogleClient(),
-		crawlReset:  atomic.NewInt64(0),
+		crawls:      make(map[string]*runningCrawl),
+		crawlsL:     &sync.Mutex{},
+		crawlsWg:    &sync.WaitGroup{},
 	}
 	s.l.SetLogLocally(true)
 	for i := 0; i < 5; err = s.l.Connect() {
@@ -59,36 +83,205 @@ func NewServer(cfg Config, errHandler func(error)) (s *Server, err error) {
 	if err != nil {
 		return nil, err
 	}
+	if s.s, err = scraper.NewMultiClient(cfg.Scraper); err != nil {
+		return nil, err
+	}
+	if cfg.Enrich.SymbolFile != "" {
+		if s.ref, err = enrich.LoadReference(cfg.Enrich.SymbolFile); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Enrich.TaxonomyFile != "" {
+		if s.tax, err = enrich.LoadTaxonomy(cfg.Enrich.TaxonomyFile); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Enrich.SentimentFile != "" {
+		if s.lex, err = enrich.LoadLexicon(cfg.Enrich.SentimentFile); err != nil {
+			return nil, err
+		}
+	}
 	s.buildRoutes()
+	s.buildPipeline()
 	return
 }
 func (s *Server) Close() error { return s.db.Close() }
 
-func (s *Server) documentProcessor() {
+// documentProcessor creates the documents sent on newDocsChan. Once ctx is
+// done it creates the ones still queued and returns.
+func (s *Server) documentProcessor(ctx context.Context) {
 	for {
-		d := <-s.newDocsChan
-		fmt.Printf("processing document:\n\t%+v\n", *d)
-		err := d.Create()
-		if err != nil {
-			fmt.Println("err", err.Error())
-			s.err("create", d.Source, err.Error())
+		select {
+		case d := <-s.newDocsChan:
+			s.createDocument(d)
+		case <-ctx.Done():
+			for {
+				select {
+				case d := <-s.newDocsChan:
+					s.createDocument(d)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (s *Server) createDocument(d *obj.Document) {
+	fmt.Printf("processing document:\n\t%+v\n", *d)
+	err := d.Create()
+	if errors.Is(err, obj.ErrClosed) {
+		s.storePending(d)
+		return
+	}
+	if err != nil {
+		fmt.Println("err", err.Error())
+		s.err("create", d.Source, err.Error())
+		return
+	}
+}
+
+// persist stores a saved document and indexes its text. It runs as the last
+// stage of the document save pipeline. The document is stored even if its
+// facts could not be.
+func (s *Server) persist(d *obj.Document, text string) error {
+	err := s.storeFacts(d)
+	if perr := s.db.Put(d.Id, d); perr != nil {
+		return perr
+	}
+	if text != "" {
+		if ierr := s.idx.Add(d.Id, text); err == nil {
+			err = ierr
+		}
+	}
+	return err
+}
+
+// Serve handles requests until ctx is canceled and then shuts down: it stops
+// accepting requests, interrupts running crawls and drains the document
+// queues. Documents that could not be saved in time are stored and queued
+// again by the next Serve.
+func (s *Server) Serve(ctx context.Context) error {
+	procCtx, stopProc := context.WithCancel(context.Background())
+	procDone := make(chan struct{})
+	go func() {
+		s.documentProcessor(procCtx)
+		close(procDone)
+	}()
+	requeueCtx, stopRequeue := context.WithCancel(context.Background())
+	requeueDone := make(chan struct{})
+	go func() {
+		s.requeuePending(requeueCtx)
+		close(requeueDone)
+	}()
+	s.resumeCrawls()
+
+	// requests, scrapes included, are canceled once shutdown begins
+	reqCtx, cancelReqs := context.WithCancel(context.Background())
+	defer cancelReqs()
+	srv := &http.Server{
+		Addr:        fmt.Sprintf(":%d", s.c.ServePort),
+		Handler:     s.router,
+		BaseContext: func(net.Listener) context.Context { return reqCtx },
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+	fmt.Println("listening on ", srv.Addr)
+
+	var err error
+	select {
+	case err = <-errc:
+	case <-ctx.Done():
+	}
+	s.log("shutting down")
+	cancelReqs()
+	sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if serr := srv.Shutdown(sctx); serr != nil && err == nil {
+		err = serr
+	}
+	s.stopCrawls()
+	stopRequeue()
+	<-requeueDone
+	stopProc()
+	<-procDone
+
+	unsaved, serr := obj.Shutdown(sctx)
+	if serr != nil {
+		s.err("shutdown", "documents", serr.Error())
+	}
+	for _, d := range unsaved {
+		s.storePending(d)
+	}
+	return err
+}
+
+// storePending keeps a document that was not saved so the next Serve can
+// queue it again.
+func (s *Server) storePending(d *obj.Document) {
+	if err := s.db.Put(prefixPendingDoc+obj.GetSignature([]byte(d.Source)), d); err != nil {
+		s.err("pending", d.Source, err.Error())
+	}
+}
+
+// requeuePending sends the documents left over by the last shutdown to the
+// document processor.
+func (s *Server) requeuePending(ctx context.Context) {
+	for _, k := range s.db.Keys(prefixPendingDoc) {
+		var d = new(obj.Document)
+		if err := s.db.Get(k, d); err != nil {
+			s.err("pending", k, err.Error())
 			continue
 		}
-		fmt.Println("putting", d.Id)
-		s.db.Put(d.Id, d)
+		select {
+		case s.newDocsChan <- d:
+		case <-ctx.Done():
+			return
+		}
+		if err := s.db.Delete(k); err != nil {
+			s.err("pending", k, err.Error())
+		}
 	}
 }
 
-func (s *Server) Serve() error {
-	go s.documentProcessor()
-	fmt.Println("listening on ", fmt.Sprintf(":%d", s.c.ServePort))
-	return http.ListenAndServe(fmt.Sprintf(":%d", s.c.ServePort), s.router)
+// buildPipeline registers the stages run on every saved document. persist
+// must stay last so the enriched document is what gets stored.
+func (s *Server) buildPipeline() {
+	obj.AddStage(enrich.NewSymbolExtractor(s.ref, s.c.Enrich))
+	if s.tax != nil {
+		obj.AddStage(enrich.NewSectorClassifier(s.tax, s.ref, s.c.Enrich))
+	}
+	obj.AddStage(enrich.NewTypeClassifier(s.c.Enrich))
+	if s.lex != nil {
+		obj.AddStage(enrich.NewSentimentScorer(s.lex))
+	}
+	obj.AddStage(enrich.NewFactExtractor(s.ref))
+	obj.AddStage(obj.StageFunc(s.ingestXBRL))
+	obj.AddStage(obj.StageFunc(s.persist))
 }
 
 func (s *Server) buildRoutes() {
 	s.router.HandleFunc("/scrape/{symbol}/{filetype}", s.Scrape)
 	s.router.HandleFunc("/query", s.Query)
-	s.router.HandleFunc("/crawl", s.crawl)
+	s.router.HandleFunc("/crawl", s.crawl).Methods(http.MethodPost)
+	s.router.HandleFunc("/crawls", s.crawl).Methods(http.MethodPost)
+	s.router.HandleFunc("/crawls", s.crawlJobs).Methods(http.MethodGet)
+	s.router.HandleFunc("/crawls/{id}", s.crawlJob).Methods(http.MethodGet)
+	s.router.HandleFunc("/crawls/{id}", s.cancelCrawl).Methods(http.MethodDelete)
+	s.router.HandleFunc("/crawls/{id}/pause", s.pauseCrawl).Methods(http.MethodPost)
+	s.router.HandleFunc("/crawls/{id}/resume", s.resumeCrawl).Methods(http.MethodPost)
+	s.router.HandleFunc("/search", s.Search)
+	s.router.HandleFunc("/search/reindex", s.Reindex).Methods(http.MethodPost)
+	s.router.HandleFunc("/facts", s.Facts).Methods(http.MethodGet)
+	s.router.HandleFunc("/fundamentals", s.Fundamentals).Methods(http.MethodGet)
+	s.router.HandleFunc("/sentiment", s.Sentiment).Methods(http.MethodGet)
+	s.router.HandleFunc("/documents/{id}", s.Document).Methods(http.MethodGet)
+	s.router.HandleFunc("/documents/{id}/tables", s.Tables).Methods(http.MethodGet)
+	s.router.HandleFunc("/documents/{id}/tables/{n}", s.Table).Methods(http.MethodGet)
+	s.router.HandleFunc("/documents/{id}/refresh", s.Refresh).Methods(http.MethodPost)
+	s.router.HandleFunc("/documents/{id}/versions", s.Versions).Methods(http.MethodGet)
+	s.router.HandleFunc("/documents/{id}/versions/{n}", s.Version).Methods(http.MethodGet)
+	s.router.HandleFunc("/documents/{id}/versions/{n}/diff", s.VersionDiff).Methods(http.MethodGet)
 }
 
 func (s *Server) Query(w http.ResponseWriter, r *http.Request) {
@@ -110,7 +303,7 @@ func (s *Server) Query(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := s.v.Where(func(i interface{}) bool { return q.Match(i) })
+	res, err := s.db.Where(q)
 
 	if err != nil {
 		s.err("query", r.RemoteAddr, r.URL.EscapedPath(), err.Error())
@@ -126,6 +319,8 @@ func (s *Server) Query(w http.ResponseWriter, r *http.Request) {
 
 }
 
+// Scrape searches the enabled engines for documents about a symbol.
+// Optional query parameters: template, company, year, pages and max_results.
 func (s *Server) Scrape(w http.ResponseWriter, r *http.Request) {
 	enableCors(w)
 
@@ -139,10 +334,53 @@ func (s *Server) Scrape(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	d, err := s.s.Scrape(symbol, ftype)
+	req := scraper.Request{
+		Symbol:   symbol,
+		FileType: ftype,
+		Company:  r.URL.Query().Get("company"),
+		Template: r.URL.Query().Get("template"),
+	}
+	for param, v := range map[string]*int{"year": &req.Year, "pages": &req.Pages, "max_results": &req.MaxResults} {
+		if q := r.URL.Query().Get(param); q != "" {
+			n, err := strconv.Atoi(q)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid "+param, http.StatusBadRequest)
+				s.err(requestSummary(r)...)
+				return
+			}
+			*v = n
+		}
+	}
+	if c, ok := s.ref.Lookup(symbol); ok && req.Company == "" {
+		req.Company = c.Name
+	}
+	if _, err := s.c.Scraper.Query(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		s.err(requestSummary(r)...)
+		return
+	}
+
+	d, err := s.s.Scrape(r.Context(), req)
 	if err != nil {
 		s.err("scrape", err.Error())
 	}
+	if len(d) == 0 && err != nil {
+		s.scrapeError(w, err)
+		return
+	}
+	if engineErrs, ok := err.(scraper.EngineErrors); ok {
+		blocked := make([]string, 0, len(engineErrs))
+		for name, err := range engineErrs {
+			if errors.Is(err, scraper.ErrBlocked) {
+				blocked = append(blocked, name)
+			}
+		}
+		sort.Strings(blocked)
+		w.Header().Set("x-blocked-engines", strings.Join(blocked, ","))
+	}
+	if d == nil {
+		d = []*obj.Document{}
+	}
 	for _, v := range d {
 		s.newDocsChan <- v
 	}
@@ -159,56 +397,42 @@ func (s *Server) Scrape(w http.ResponseWriter, r *http.Request) {
 
 }
 
-func (s *Server) crawl(w http.ResponseWriter, r *http.Request) {
-	enableCors(w)
-	if s.crawlReset.Load() > time.Now().Unix() {
-		w.Header().Set("x-ratelimit-reset", fmt.Sprintf("%d", s.crawlReset.Load()))
-		http.Error(w, "too many requests", http.StatusTooManyRequests)
-		s.err("crawl", r.RemoteAddr)
-		return
-	}
-
-	b, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		s.err("crawl", r.RemoteAddr, err.Error())
-		return
-	}
+type scrapeErrorResponse struct {
+	Error      string
+	RetryAfter int               `json:",omitempty"` // seconds
+	Engines    map[string]string `json:",omitempty"`
+}
 
-	s.crawlReset.Store(time.Now().Unix() + 60)
+// scrapeError responds to a scrape that found nothing because every engine
+// failed. It answers 503 with a Retry-After header when the engines are
+// blocking us and 502 otherwise.
+func (s *Server) scrapeError(w http.ResponseWriter, err error) {
+	res := scrapeErrorResponse{Error: err.Error()}
+	status := http.StatusBadGateway
 
-	var params scraper.CrawlerParams
-	err = json.Unmarshal(b, &params)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		s.err("crawl", r.RemoteAddr, err.Error())
-		return
+	var blocked *scraper.BlockedError
+	engineErrs, isEngineErrs := err.(scraper.EngineErrors)
+	if isEngineErrs {
+		res.Engines = make(map[string]string, len(engineErrs))
+		for name, err := range engineErrs {
+			res.Engines[name] = err.Error()
+		}
+		blocked, _ = engineErrs.Blocked()
+	} else {
+		errors.As(err, &blocked)
 	}
-
-	c, err := scraper.NewCrawler(params)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		s.err("crawl", r.RemoteAddr, err.Error())
-		return
+	if blocked != nil {
+		status = http.StatusServiceUnavailable
+		res.RetryAfter = int(math.Ceil(blocked.RetryAfter.Seconds()))
+		w.Header().Set("retry-after", strconv.Itoa(res.RetryAfter))
 	}
 
-	c.SetApplicationFileHandler(func(r *http.Response) error {
-		if r.Header.Get("content-type") == "application/pdf" {
-			doc := obj.NewDocument(r)
-			s.newDocsChan <- doc
-		}
-		return nil
-	})
-
-	c.SetTextFileHandler(func(r *http.Response) error {
-		doc := obj.NewDocument(r)
-		s.newDocsChan <- doc
-		return nil
-	})
-	s.log("crawling")
-	c.SetLogger(s.l)
-	c.Crawl(8)
-
+	b, _ := json.Marshal(res)
+	w.Header().Set("content-type", "application/json")
+	w.WriteHeader(status)
+	if _, err = w.Write(b); err != nil {
+		s.err("write", err.Error())
+	}
 }
 
 func enableCors(w http.ResponseWriter) {
@@ -31,3 +31,12 @@ func (s *Server) err(v ...string) {
 func (s *Server) log(v ...string) {
 	s.l.Write(append([]string{"SCRAPER", "API", "LOG"}, v...)...)
 }
+
+func contains(v []string, s string) bool {
+	for _, val := range v {
+		if val == s {
+			return true
+		}
+	}
+	return false
+}
@@ -5,6 +5,7 @@ import (
 	"compress/gzip"
 	"encoding/json"
 	"errors"
+	"io"
 
 	badger "github.com/dgraph-io/badger/v3"
 	"github.com/mrod502/stockscraper/obj"
@@ -41,17 +42,6 @@ type DB struct {
 }
 
 func New(cfg Config) (db *DB, err error) {
-
-	d, err := badger.Open(cfg.BadgerOpts)
-
-	if err != nil {
-		return nil, err
-	}
-
-	db = &DB{
-		db:  d,
-		cfg: cfg,
-	}
 	var marshal func(interface{}) ([]byte, error)
 	var unmarshal func([]byte, interface{}) error
 	switch cfg.Encoding {
@@ -67,9 +57,44 @@ func New(cfg Config) (db *DB, err error) {
 	default:
 		return nil, ErrUnsupportedEncoding
 	}
+
+	d, err := badger.Open(cfg.BadgerOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	db = &DB{
+		db:        d,
+		cfg:       cfg,
+		marshal:   marshal,
+		unmarshal: unmarshal,
+	}
 	if cfg.Compress {
 		db.marshal = func(v interface{}) ([]byte, error) {
-			gzip.NewWriter()
+			b, err := marshal(v)
+			if err != nil {
+				return nil, err
+			}
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			if _, err = w.Write(b); err != nil {
+				return nil, err
+			}
+			if err = w.Close(); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		}
+		db.unmarshal = func(b []byte, v interface{}) error {
+			r, err := gzip.NewReader(bytes.NewReader(b))
+			if err != nil {
+				return err
+			}
+			defer r.Close()
+			if b, err = io.ReadAll(r); err != nil {
+				return err
+			}
+			return unmarshal(b, v)
 		}
 	}
 	return
@@ -96,11 +121,24 @@ func (d *DB) Put(k string, v any) error {
 		if err != nil {
 			return err
 		}
-		if err = txn.Set([]byte(k), b); err != nil {
+		return txn.Set([]byte(k), b)
+	})
+}
+
+// PutMany writes every key in kv using a single write batch.
+func (d *DB) PutMany(kv map[string]any) error {
+	wb := d.db.NewWriteBatch()
+	defer wb.Cancel()
+	for k, v := range kv {
+		b, err := d.marshal(v)
+		if err != nil {
 			return err
 		}
-		return txn.Commit()
-	})
+		if err = wb.Set([]byte(k), b); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
 }
 
 func (d *DB) Exists(k string) (bool, error) {
@@ -123,12 +161,24 @@ func (d *DB) Delete(k string) error {
 	})
 }
 
+// DeleteMany removes every key in keys using a single write batch.
+func (d *DB) DeleteMany(keys []string) error {
+	wb := d.db.NewWriteBatch()
+	defer wb.Cancel()
+	for _, k := range keys {
+		if err := wb.Delete([]byte(k)); err != nil {
+			return err
+		}
+	}
+	return wb.Flush()
+}
+
 func (d *DB) Keys(prefix string) (keys []string) {
 	keys = make([]string, 0)
 	d.db.View(func(t *badger.Txn) error {
 		iterator := t.NewIterator(badger.DefaultIteratorOptions)
 		defer iterator.Close()
-		for iterator.Seek([]byte(prefix)); iterator.Valid(); iterator.Next() {
+		for iterator.Seek([]byte(prefix)); iterator.ValidForPrefix([]byte(prefix)); iterator.Next() {
 			key := iterator.Item().Key()
 			keys = append(keys, string(key))
 		}
@@ -137,6 +187,27 @@ func (d *DB) Keys(prefix string) (keys []string) {
 	return
 }
 
+// Scan calls fn for every key with the given prefix. decode unmarshals the
+// current value into v and is only valid for the duration of the call.
+func (d *DB) Scan(prefix string, fn func(key string, decode func(v interface{}) error) error) error {
+	return d.db.View(func(t *badger.Txn) error {
+		iterator := t.NewIterator(badger.DefaultIteratorOptions)
+		defer iterator.Close()
+		for iterator.Seek([]byte(prefix)); iterator.ValidForPrefix([]byte(prefix)); iterator.Next() {
+			item := iterator.Item()
+			err := fn(string(item.Key()), func(v interface{}) error {
+				return item.Value(func(val []byte) error {
+					return d.unmarshal(val, v)
+				})
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func GetClass(b []byte) (string, error) {
 	ix0 := bytes.Index(b, []byte("Class")) + 6
 	if ix0 < 0 {
@@ -1,10 +1,11 @@
 package db
 
 import (
+	"strings"
+
 	"github.com/dgraph-io/badger/v3"
 	gocache "github.com/mrod502/go-cache"
 	"github.com/mrod502/stockscraper/obj"
-	"github.com/vmihailenco/msgpack/v5"
 )
 
 var (
@@ -17,44 +18,43 @@ type Query[T any] interface {
 	Match(T) bool
 }
 
-func (d *DB) Where(m Query[any]) ([]any, error) {
-	var matches uint
-	for _, key := range d.Keys() {
-		err := d.db.View(func(t *badger.Txn) error {
-			item, rerr := t.Get([]byte(key))
-			if rerr != nil {
-				return rerr
+// Matcher is satisfied by the concrete query types in this package.
+type Matcher interface {
+	Match(gocache.Object) bool
+	GetLimit() uint
+}
+
+// Where returns the stored documents matched by m. Namespaced records
+// (keys containing ":"), such as the search index, are skipped.
+func (d *DB) Where(m Matcher) (objects []any, err error) {
+	objects = make([]any, 0)
+	err = d.db.View(func(t *badger.Txn) error {
+		iterator := t.NewIterator(badger.DefaultIteratorOptions)
+		defer iterator.Close()
+		for iterator.Rewind(); iterator.Valid(); iterator.Next() {
+			item := iterator.Item()
+			if strings.Contains(string(item.Key()), ":") {
+				continue
 			}
-			return item.Value(func(b []byte) error {
-				class, err := GetClass(b)
-				if err != nil {
-					return err
-				}
-				switch class {
-				case obj.TDocument:
-					var object = new(obj.Document)
-					err = msgpack.Unmarshal(b, object)
-					if err != nil {
-						return err
-					}
-					if m.Match(object) {
-						matches++
-						objects = append(objects, object)
-					}
-				default:
-					return ErrClassNotFound
-				}
+			var object = new(obj.Document)
+			if err := item.Value(func(b []byte) error {
+				return d.unmarshal(b, object)
+			}); err != nil {
 				return err
-			})
-		})
-		if err != nil {
-			return objects, err
-		}
-		if matches >= m.GetLimit() {
-			return objects, nil
+			}
+			if object.Item == nil || object.Class != obj.TDocument {
+				continue
+			}
+			if m.Match(object) {
+				objects = append(objects, object)
+			}
+			if uint(len(objects)) >= m.GetLimit() {
+				return nil
+			}
 		}
-	}
-	return objects, nil
+		return nil
+	})
+	return
 }
 
 type ItemQuery struct {
@@ -111,6 +111,36 @@ type DocQuery struct {
 	ContentType gocache.StringQuery
 	Type        gocache.StringQuery
 	PostedDate  gocache.TimeQuery
+
+	// Sentiment scores, see obj.Sentiment. Documents that were not scored
+	// only match when these are unset.
+	Tone        RangeQuery
+	Positive    RangeQuery
+	Negative    RangeQuery
+	Uncertainty RangeQuery
+	Litigious   RangeQuery
+}
+
+// RangeQuery matches numbers between Min and Max, inclusive. A nil bound is
+// open, so the zero RangeQuery matches everything.
+type RangeQuery struct {
+	Min *float64 `json:",omitempty"`
+	Max *float64 `json:",omitempty"`
+}
+
+func (q RangeQuery) IsZero() bool { return q.Min == nil && q.Max == nil }
+
+func (q RangeQuery) Match(v float64) bool {
+	return (q.Min == nil || v >= *q.Min) && (q.Max == nil || v <= *q.Max)
+}
+
+func (d DocQuery) matchSentiment(s *obj.Sentiment) bool {
+	if s == nil {
+		return d.Tone.IsZero() && d.Positive.IsZero() && d.Negative.IsZero() &&
+			d.Uncertainty.IsZero() && d.Litigious.IsZero()
+	}
+	return d.Tone.Match(s.Tone) && d.Positive.Match(s.Positive) && d.Negative.Match(s.Negative) &&
+		d.Uncertainty.Match(s.Uncertainty) && d.Litigious.Match(s.Litigious)
 }
 
 func (d DocQuery) Match(v gocache.Object) bool {
@@ -118,7 +148,8 @@ func (d DocQuery) Match(v gocache.Object) bool {
 	return d.ItemQuery.Match(*doc.Item) && d.Title.Match(doc.Title) &&
 		d.Symbols.Match(doc.Symbols) && d.Sectors.Match(doc.Sectors) &&
 		d.Source.Match(doc.Source) && d.ContentType.Match(doc.ContentType) &&
-		d.Type.Match(doc.ContentType) && d.PostedDate.Match(doc.PostedDate)
+		d.Type.Match(doc.Type) && d.PostedDate.Match(doc.PostedDate) &&
+		d.matchSentiment(doc.Sentiment)
 
 }
 
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+func TestMatchSentiment(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	s := &obj.Sentiment{Tone: -0.4, Negative: 0.02, Uncertainty: 0.01}
+
+	if !(DocQuery{}).matchSentiment(nil) || !(DocQuery{}).matchSentiment(s) {
+		t.Fatal("unset ranges must match everything")
+	}
+	if (DocQuery{Tone: RangeQuery{Max: f(0)}}).matchSentiment(nil) {
+		t.Fatal("unscored document matched a tone range")
+	}
+	for _, c := range []struct {
+		q    DocQuery
+		want bool
+	}{
+		{DocQuery{Tone: RangeQuery{Max: f(0)}}, true},
+		{DocQuery{Tone: RangeQuery{Min: f(-0.4), Max: f(-0.4)}}, true},
+		{DocQuery{Tone: RangeQuery{Min: f(0)}}, false},
+		{DocQuery{Negative: RangeQuery{Min: f(0.01)}, Uncertainty: RangeQuery{Max: f(0.005)}}, false},
+	} {
+		if got := c.q.matchSentiment(s); got != c.want {
+			t.Errorf("%+v = %v", c.q, got)
+		}
+	}
+}
@@ -0,0 +1,14 @@
+package enrich
+
+type Config struct {
+	SymbolFile    string  `yaml:"symbol_file"` // csv or json symbol reference, see LoadReference
+	MinConfidence float64 `yaml:"min_confidence"`
+	MaxSymbols    int     `yaml:"max_symbols"`
+
+	TaxonomyFile   string  `yaml:"taxonomy_file"` // json or csv sector taxonomy, see LoadTaxonomy
+	MinSectorScore float64 `yaml:"min_sector_score"`
+
+	MinTypeScore float64 `yaml:"min_type_score"`
+
+	SentimentFile string `yaml:"sentiment_file"` // json word lists or the Loughran-McDonald csv, see LoadLexicon
+}
@@ -0,0 +1,84 @@
+package enrich
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+// Consensus summarizes the latest view of each firm on one kind of fact:
+// the spread of their price targets or estimates for a period, or how many
+// rate the symbol buy, hold or sell.
+type Consensus struct {
+	Kind     string
+	Period   string `json:",omitempty"`
+	Currency string `json:",omitempty"`
+	Count    int
+	Mean     float64        `json:",omitempty"`
+	Low      float64        `json:",omitempty"`
+	High     float64        `json:",omitempty"`
+	Ratings  map[string]int `json:",omitempty"`
+	Updated  time.Time
+}
+
+// Summarize returns the consensus of facts about a symbol, one entry per
+// kind, period and currency. Only a firm's most recent fact counts; facts
+// with no known firm count once per document.
+func Summarize(facts []obj.Fact) []Consensus {
+	type group struct{ kind, period, currency string }
+	latest := make(map[group]map[string]obj.Fact)
+	for _, f := range facts {
+		g := group{f.Kind, f.Period, f.Currency}
+		if latest[g] == nil {
+			latest[g] = make(map[string]obj.Fact)
+		}
+		source := f.Firm
+		if source == "" {
+			source = "doc:" + f.Document
+		}
+		if prev, ok := latest[g][source]; !ok || f.Date.After(prev.Date) {
+			latest[g][source] = f
+		}
+	}
+
+	out := make([]Consensus, 0, len(latest))
+	for g, bySource := range latest {
+		c := Consensus{Kind: g.kind, Period: g.period, Currency: g.currency, Count: len(bySource)}
+		first := true
+		for _, f := range bySource {
+			if f.Date.After(c.Updated) {
+				c.Updated = f.Date
+			}
+			if f.Kind == obj.FactRating {
+				if c.Ratings == nil {
+					c.Ratings = make(map[string]int)
+				}
+				c.Ratings[f.Rating]++
+				continue
+			}
+			c.Mean += f.Value
+			if first || f.Value < c.Low {
+				c.Low = f.Value
+			}
+			if first || f.Value > c.High {
+				c.High = f.Value
+			}
+			first = false
+		}
+		if g.kind != obj.FactRating {
+			c.Mean /= float64(c.Count)
+		}
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Kind != out[j].Kind {
+			return out[i].Kind < out[j].Kind
+		}
+		if out[i].Period != out[j].Period {
+			return out[i].Period < out[j].Period
+		}
+		return out[i].Currency < out[j].Currency
+	})
+	return out
+}
@@ -0,0 +1,188 @@
+package enrich
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+const (
+	DefaultMinTypeScore = 3.0
+	typeTextHead        = 20000 // bytes of text searched by KeywordRule
+	maxTextHits         = 5
+)
+
+// TypeRule scores how likely a document is of some type. Rules return an
+// empty type when they have no opinion.
+type TypeRule interface {
+	Classify(doc *obj.Document, text string) (typ string, score float64)
+}
+
+type TypeRuleFunc func(doc *obj.Document, text string) (string, float64)
+
+func (f TypeRuleFunc) Classify(doc *obj.Document, text string) (string, float64) {
+	return f(doc, text)
+}
+
+// KeywordRule scores a type by looking for phrases in the title and the head
+// of the text, and by matching the source host, path and content type. All
+// matching is case insensitive.
+type KeywordRule struct {
+	Type         string
+	Title        []string
+	Text         []string
+	Domains      []string // host suffixes, e.g. "sec.gov"
+	Paths        []string // path substrings, e.g. "/blog/"
+	ContentTypes []string
+}
+
+func (k KeywordRule) Classify(doc *obj.Document, text string) (string, float64) {
+	var score float64
+	title := strings.ToLower(doc.Title)
+	for _, v := range k.Title {
+		if strings.Contains(title, v) {
+			score += 3
+			break
+		}
+	}
+	if u, err := url.Parse(doc.Source); err == nil {
+		host := strings.ToLower(u.Hostname())
+		for _, v := range k.Domains {
+			if host == v || strings.HasSuffix(host, "."+v) {
+				score += 3
+				break
+			}
+		}
+		p := strings.ToLower(u.Path)
+		for _, v := range k.Paths {
+			if strings.Contains(p, v) {
+				score += 2
+				break
+			}
+		}
+	}
+	for _, v := range k.ContentTypes {
+		if strings.Contains(doc.ContentType, v) {
+			score++
+			break
+		}
+	}
+	if len(text) > typeTextHead {
+		text = text[:typeTextHead]
+	}
+	text = strings.ToLower(text)
+	hits := 0
+	for _, v := range k.Text {
+		if hits < maxTextHits && strings.Contains(text, v) {
+			hits++
+		}
+	}
+	return k.Type, score + float64(hits)
+}
+
+var DefaultTypeRules = []TypeRule{
+	KeywordRule{
+		Type:    obj.Type10K,
+		Title:   []string{"10-k", "annual report"},
+		Text:    []string{"form 10-k", "annual report pursuant to section 13 or 15(d)", "for the fiscal year ended", "item 1a. risk factors", "item 7. management"},
+		Domains: []string{"sec.gov"},
+	},
+	KeywordRule{
+		Type:    obj.Type10Q,
+		Title:   []string{"10-q", "quarterly report"},
+		Text:    []string{"form 10-q", "quarterly report pursuant to section 13 or 15(d)", "for the quarterly period ended", "part i. financial information"},
+		Domains: []string{"sec.gov"},
+	},
+	KeywordRule{
+		Type:    obj.Type8K,
+		Title:   []string{"8-k", "current report"},
+		Text:    []string{"form 8-k", "current report pursuant to section 13 or 15(d)", "date of report (date of earliest event reported)", "item 2.02", "item 9.01"},
+		Domains: []string{"sec.gov"},
+	},
+	KeywordRule{
+		Type:  obj.TypeTranscript,
+		Title: []string{"transcript", "earnings call", "conference call"},
+		Text:  []string{"earnings call", "conference call", "operator:", "question-and-answer session", "[operator instructions]", "prepared remarks", "next question"},
+		Paths: []string{"transcript"},
+	},
+	KeywordRule{
+		Type:    obj.TypeResearch,
+		Title:   []string{"equity research", "initiating coverage", "initiation", "price target"},
+		Text:    []string{"price target", "analyst certification", "important disclosures", "equity research", "overweight", "underweight", "rating:", "target price", "investment thesis"},
+		Domains: []string{"morganstanley.com", "ms.com", "gs.com", "jpmorgan.com", "jpm.com", "bofa.com", "baml.com", "citi.com", "ubs.com", "barclays.com", "credit-suisse.com", "db.com", "jefferies.com", "piper.com", "raymondjames.com"},
+		Paths:   []string{"research"},
+	},
+	KeywordRule{
+		Type:         obj.TypePresentation,
+		Title:        []string{"presentation", "investor day", "analyst day", "earnings deck"},
+		Text:         []string{"investor presentation", "investor day", "forward-looking statements", "safe harbor", "non-gaap reconciliation", "key highlights"},
+		Paths:        []string{"presentation", "investor-day", "/events/"},
+		ContentTypes: []string{"presentation", "powerpoint"},
+	},
+	KeywordRule{
+		Type:         obj.TypeNews,
+		Text:         []string{"(reuters) -", "(bloomberg) --", "(ap) —", "associated press", "reporting by", "editing by"},
+		Domains:      []string{"reuters.com", "bloomberg.com", "cnbc.com", "wsj.com", "ft.com", "marketwatch.com", "finance.yahoo.com", "barrons.com", "businesswire.com", "prnewswire.com", "globenewswire.com", "apnews.com"},
+		Paths:        []string{"/news/", "/article/"},
+		ContentTypes: []string{"text/html"},
+	},
+	KeywordRule{
+		Type:         obj.TypeBlog,
+		Title:        []string{"blog"},
+		Text:         []string{"subscribe to", "leave a comment", "posted by", "comments"},
+		Domains:      []string{"substack.com", "medium.com", "wordpress.com", "blogspot.com", "seekingalpha.com"},
+		Paths:        []string{"/blog", "/post/", "/posts/"},
+		ContentTypes: []string{"text/html"},
+	},
+}
+
+// TypeClassifier labels documents with the type whose rules score highest.
+type TypeClassifier struct {
+	rules    []TypeRule
+	minScore float64
+}
+
+func NewTypeClassifier(cfg Config) *TypeClassifier {
+	c := &TypeClassifier{
+		rules:    append([]TypeRule{}, DefaultTypeRules...),
+		minScore: cfg.MinTypeScore,
+	}
+	if c.minScore <= 0 {
+		c.minScore = DefaultMinTypeScore
+	}
+	return c
+}
+
+func (c *TypeClassifier) AddRule(r TypeRule) {
+	c.rules = append(c.rules, r)
+}
+
+// Classify returns the best scoring type, or "" when no type reaches the
+// minimum score.
+func (c *TypeClassifier) Classify(doc *obj.Document, text string) string {
+	scores := make(map[string]float64)
+	for _, r := range c.rules {
+		if typ, score := r.Classify(doc, text); typ != "" {
+			scores[typ] += score
+		}
+	}
+	best, bestScore := "", 0.0
+	for typ, score := range scores {
+		if score > bestScore || score == bestScore && typ < best {
+			best, bestScore = typ, score
+		}
+	}
+	if bestScore < c.minScore {
+		return ""
+	}
+	return best
+}
+
+// Process sets doc.Type unless the scraper already knew it.
+func (c *TypeClassifier) Process(doc *obj.Document, text string) error {
+	if doc.Type == "" {
+		doc.Type = c.Classify(doc, text)
+	}
+	return nil
+}
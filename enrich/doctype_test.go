@@ -0,0 +1,28 @@
+package enrich
+
+import (
+	"testing"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+func TestTypeClassifier(t *testing.T) {
+	c := NewTypeClassifier(Config{})
+	for _, tc := range []struct {
+		doc  obj.Document
+		text string
+		want string
+	}{
+		{obj.Document{Source: "https://www.sec.gov/Archives/edgar/data/320193/aapl-20230930.htm"},
+			"UNITED STATES SECURITIES AND EXCHANGE COMMISSION FORM 10-K ANNUAL REPORT PURSUANT TO SECTION 13 OR 15(d) For the fiscal year ended September 30, 2023", obj.Type10K},
+		{obj.Document{Title: "NVIDIA Q3 2024 Earnings Call Transcript"},
+			"Operator: Good afternoon. ... Question-and-Answer Session ... Our next question comes from", obj.TypeTranscript},
+		{obj.Document{Source: "https://example.com/research/nvda.pdf", ContentType: "application/pdf"},
+			"We reiterate Overweight with a price target of $650. Analyst Certification ... Important Disclosures", obj.TypeResearch},
+		{obj.Document{Source: "https://example.com/x"}, "nothing to see", ""},
+	} {
+		if got := c.Classify(&tc.doc, tc.text); got != tc.want {
+			t.Fatalf("Classify(%q) = %q, want %q", tc.doc.Source+tc.doc.Title, got, tc.want)
+		}
+	}
+}
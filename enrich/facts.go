@@ -0,0 +1,448 @@
+package enrich
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+const (
+	factTextHead  = 5000 // bytes of text searched for the issuing firm and analyst
+	maxFactWindow = 80   // bytes after a keyword searched for its value
+	maxContext    = 300
+)
+
+// DefaultFirms are the research firms recognized by a FactExtractor.
+var DefaultFirms = []string{
+	"Goldman Sachs", "Morgan Stanley", "J.P. Morgan", "JPMorgan", "Bank of America",
+	"BofA", "Citigroup", "Citi", "Barclays", "UBS", "Credit Suisse", "Deutsche Bank",
+	"Jefferies", "Wells Fargo", "Evercore", "Bernstein", "Piper Sandler",
+	"Raymond James", "RBC Capital", "Cowen", "Needham", "Oppenheimer", "Mizuho",
+	"KeyBanc", "Stifel", "Wedbush", "BMO Capital", "Baird", "Truist", "Macquarie",
+	"HSBC", "Nomura", "Loop Capital", "Rosenblatt", "Morningstar", "Argus", "CFRA",
+	"Cantor Fitzgerald", "Canaccord", "Benchmark", "BTIG", "Guggenheim",
+}
+
+var (
+	rexTargetKey = regexp.MustCompile(`(?i)\b(?:price target|target price|price objective|PT|TP)\b`)
+	rexRating    = regexp.MustCompile(`(?i)\b(?:rating|rated|reiterates?d?|maintain(?:s|ed)?|upgrade[sd]?(?: \w+)? to|downgrade[sd]?(?: \w+)? to|initiat(?:e|es|ed|ing)(?: coverage)?(?: of \S+)?(?: with| at)|recommendation)\W{1,3}(?:an?\s+|our\s+)?(strong buy|buy|outperform|overweight|accumulate|add|positive|hold|neutral|market perform|equal[- ]weight|sector perform|in-line|peer perform|sell|underperform|underweight|reduce|negative)\b`)
+	rexEPSKey    = regexp.MustCompile(`(?i)\b(?:EPS|earnings per share)\b`)
+	rexRevKey    = regexp.MustCompile(`(?i)\b(?:revenues?|sales)\b`)
+	rexEstimate  = regexp.MustCompile(`(?i)\b(?:estimates?d?|expects?|expected|forecasts?|projects?|projected|guidance|guides|consensus|we see|outlook)\b`)
+
+	rexPrice   = regexp.MustCompile(`(US\$|[$€£])?\s?(\d{1,3}(?:,\d{3})+|\d+)(\.\d+)?(%)?`)
+	rexEPS     = regexp.MustCompile(`(US\$|[$€£])?\s?(\(|-)?(\d{1,3}\.\d{1,2})\)?(%)?`)
+	rexRevenue = regexp.MustCompile(`(?i)(US\$|[$€£])?\s?(\d[\d,]*(?:\.\d+)?)\s?(billion|bn|b|million|mn|mm|m)\b`)
+	rexPeriod  = regexp.MustCompile(`(?i)\b(?:(FY|CY|F)\s?'?(\d{4}|\d{2})E?|(?:(Q[1-4])|([1-4])Q)\s?(?:FY|CY)?\s?'?(\d{4}|\d{2})E?|fiscal(?: year)?\s+(\d{4})|((?:19|20)\d{2})E)\b`)
+
+	rexAnalystAfter  = regexp.MustCompile(`(?i:analysts?)[:,]?\s+([A-Z][a-z]+(?:\s[A-Z]\.)?\s[A-Z][a-zA-Z'\-]+)`)
+	rexAnalystBefore = regexp.MustCompile(`([A-Z][a-z]+(?:\s[A-Z]\.)?\s[A-Z][a-zA-Z'\-]+),?\s+(?:CFA,?\s+)?(?:an?\s+|the\s+)?(?i:analyst)\b`)
+
+	rexSentenceTicker = regexp.MustCompile(`\b[A-Z]{2,5}(?:\.[A-Z])?\b`)
+
+	ratings = map[string]string{
+		"strong buy": obj.RatingBuy, "buy": obj.RatingBuy, "outperform": obj.RatingBuy,
+		"overweight": obj.RatingBuy, "accumulate": obj.RatingBuy, "add": obj.RatingBuy,
+		"positive": obj.RatingBuy,
+		"hold":     obj.RatingHold, "neutral": obj.RatingHold, "market perform": obj.RatingHold,
+		"equal weight": obj.RatingHold, "equal-weight": obj.RatingHold,
+		"sector perform": obj.RatingHold, "in-line": obj.RatingHold, "peer perform": obj.RatingHold,
+		"sell": obj.RatingSell, "underperform": obj.RatingSell, "underweight": obj.RatingSell,
+		"reduce": obj.RatingSell, "negative": obj.RatingSell,
+	}
+	currencies = map[string]string{"$": "USD", "US$": "USD", "€": "EUR", "£": "GBP"}
+	scales     = map[string]float64{
+		"billion": 1e9, "bn": 1e9, "b": 1e9,
+		"million": 1e6, "mn": 1e6, "mm": 1e6, "m": 1e6,
+	}
+)
+
+// FactExtractor finds price targets, ratings and EPS and revenue estimates in
+// document text. Each fact is linked to a symbol mentioned in its sentence,
+// or to the document's first symbol.
+type FactExtractor struct {
+	ref   *Reference
+	firms []string
+}
+
+func NewFactExtractor(ref *Reference) *FactExtractor {
+	return &FactExtractor{ref: ref, firms: append([]string{}, DefaultFirms...)}
+}
+
+func (e *FactExtractor) AddFirm(name string) {
+	e.firms = append(e.firms, name)
+}
+
+// value is a figure found in a sentence.
+type value struct {
+	v          float64
+	currency   string
+	start, end int
+}
+
+// Extract returns the facts stated in text. Document and Date are left for
+// Process to set, and Symbol is empty when neither the sentence nor
+// doc.Symbols name one.
+func (e *FactExtractor) Extract(doc *obj.Document, text string) []obj.Fact {
+	head := text
+	if len(head) > factTextHead {
+		head = head[:factTextHead]
+	}
+	docFirm, docAnalyst := e.findFirm(head), findAnalyst(head)
+
+	facts := make([]obj.Fact, 0)
+	seen := make(map[obj.Fact]bool)
+	add := func(f obj.Fact, sentence string) {
+		if f.Firm == "" {
+			f.Firm = docFirm
+		}
+		if f.Analyst == "" {
+			f.Analyst = docAnalyst
+		}
+		f.Symbol = e.sentenceSymbol(doc, sentence)
+		if seen[f] {
+			return
+		}
+		seen[f] = true
+		if len(sentence) > maxContext {
+			sentence = sentence[:maxContext]
+		}
+		f.Context = sentence
+		facts = append(facts, f)
+	}
+
+	for _, s := range splitSentences(text) {
+		firm, analyst := e.findFirm(s), findAnalyst(s)
+		base := obj.Fact{Firm: firm, Analyst: analyst}
+
+		if loc := rexTargetKey.FindStringIndex(s); loc != nil {
+			if v, ok := pickValue(s, loc[1], preferCurrency(priceValues(s, loc[1]))); ok {
+				f := base
+				f.Kind, f.Value, f.Currency = obj.FactPriceTarget, v.v, v.currency
+				add(f, s)
+			}
+		}
+		if m := rexRating.FindStringSubmatch(s); m != nil {
+			label := strings.ToLower(m[1])
+			f := base
+			f.Kind, f.Rating, f.Label = obj.FactRating, ratings[label], titleCase(label)
+			add(f, s)
+		}
+		if !rexEstimate.MatchString(s) && !rexPeriod.MatchString(s) {
+			continue
+		}
+		for _, metric := range []struct {
+			kind   string
+			key    *regexp.Regexp
+			other  *regexp.Regexp
+			values func(s string, from int) []value
+		}{
+			{obj.FactEPS, rexEPSKey, rexRevKey, epsValues},
+			{obj.FactRevenue, rexRevKey, rexEPSKey, revenueValues},
+		} {
+			loc := metric.key.FindStringIndex(s)
+			if loc == nil {
+				continue
+			}
+			periods := findPeriods(s)
+			if len(periods) == 0 {
+				continue
+			}
+			// the figures for this metric end where the other one is named
+			seg := s
+			if o := metric.other.FindStringIndex(s[loc[1]:]); o != nil {
+				seg = s[:loc[1]+o[0]]
+			}
+			values := metric.values(seg, loc[1])
+			if len(values) == 0 {
+				// "$3.20 EPS"
+				values = metric.values(seg, 0)
+			}
+			if len(values) == 0 {
+				continue
+			}
+			if len(periods) > 1 && len(periods) == len(values) {
+				// "FY24 and FY25 EPS of $3.20 and $3.80"
+				for i := range periods {
+					f := base
+					f.Kind, f.Period, f.Value, f.Currency = metric.kind, periods[i], values[i].v, values[i].currency
+					add(f, s)
+				}
+				continue
+			}
+			if v, ok := pickValue(s, loc[1], values); ok {
+				f := base
+				f.Kind, f.Period, f.Value, f.Currency = metric.kind, periods[0], v.v, v.currency
+				add(f, s)
+			}
+		}
+	}
+	return facts
+}
+
+// Process sets doc.Facts to the facts found in its text that could be linked
+// to a symbol.
+func (e *FactExtractor) Process(doc *obj.Document, text string) error {
+	date := doc.PostedDate
+	if date.IsZero() {
+		date = doc.Fetched
+	}
+	facts := make([]obj.Fact, 0)
+	for _, f := range e.Extract(doc, text) {
+		if f.Symbol == "" {
+			continue
+		}
+		f.Document, f.Date = doc.Id, date
+		facts = append(facts, f)
+	}
+	doc.Facts = facts
+	return nil
+}
+
+// sentenceSymbol returns the document symbol named in s, or the document's
+// first symbol if s names none.
+func (e *FactExtractor) sentenceSymbol(doc *obj.Document, s string) string {
+	for _, m := range rexSentenceTicker.FindAllString(s, -1) {
+		if contains(doc.Symbols, m) {
+			return m
+		}
+	}
+	if e.ref != nil {
+		for _, sym := range e.ref.findNames(s) {
+			if contains(doc.Symbols, sym) {
+				return sym
+			}
+		}
+	}
+	if len(doc.Symbols) > 0 {
+		return doc.Symbols[0]
+	}
+	return ""
+}
+
+func (e *FactExtractor) findFirm(s string) string {
+	best, at := "", -1
+	for _, firm := range e.firms {
+		if i := indexWord(s, firm); i >= 0 && (at < 0 || i < at) {
+			best, at = firm, i
+		}
+	}
+	return best
+}
+
+// indexWord returns the index of the first occurrence of w in s that is not
+// part of a longer word, or -1.
+func indexWord(s, w string) int {
+	for off := 0; off < len(s); {
+		i := strings.Index(s[off:], w)
+		if i < 0 {
+			return -1
+		}
+		i += off
+		end := i + len(w)
+		if (i == 0 || !isWordByte(s[i-1])) && (end == len(s) || !isWordByte(s[end])) {
+			return i
+		}
+		off = i + 1
+	}
+	return -1
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func findAnalyst(s string) string {
+	if m := rexAnalystAfter.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	if m := rexAnalystBefore.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// splitSentences splits text into lines and the lines into sentences,
+// leaving abbreviations such as "J.P. Morgan" and "Inc." whole.
+func splitSentences(text string) []string {
+	out := make([]string, 0)
+	start := 0
+	flush := func(end int) {
+		if s := strings.Join(strings.Fields(text[start:end]), " "); s != "" {
+			out = append(out, s)
+		}
+		start = end
+	}
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\n':
+			flush(i)
+		case '.', '!', '?':
+			if i+1 < len(text) && text[i+1] != ' ' && text[i+1] != '\n' {
+				continue
+			}
+			if text[i] == '.' && isAbbreviation(text[start:i]) {
+				continue
+			}
+			flush(i + 1)
+		}
+	}
+	flush(len(text))
+	return out
+}
+
+var abbreviations = map[string]bool{
+	"inc": true, "corp": true, "co": true, "ltd": true, "plc": true, "vs": true,
+	"no": true, "mr": true, "ms": true, "dr": true, "st": true, "est": true, "approx": true,
+}
+
+// isAbbreviation reports whether the last word of s, which is followed by a
+// period, is an abbreviation or an initial.
+func isAbbreviation(s string) bool {
+	w := s[strings.LastIndexAny(s, " \t(")+1:]
+	return len(w) == 1 || strings.Contains(w, ".") || abbreviations[strings.ToLower(w)]
+}
+
+// findPeriods returns the fiscal periods named in s, normalized to "FY2024",
+// "CY2024" or "Q3 2024".
+func findPeriods(s string) []string {
+	periods := make([]string, 0)
+	for _, m := range rexPeriod.FindAllStringSubmatch(s, -1) {
+		var p string
+		switch {
+		case m[1] != "":
+			prefix := strings.ToUpper(m[1])
+			if prefix == "F" {
+				prefix = "FY"
+			}
+			p = prefix + fullYear(m[2])
+		case m[3] != "" || m[4] != "":
+			q := strings.ToUpper(m[3])
+			if q == "" {
+				q = "Q" + m[4]
+			}
+			p = q + " " + fullYear(m[5])
+		case m[6] != "":
+			p = "FY" + m[6]
+		default:
+			p = "FY" + m[7]
+		}
+		if !contains(periods, p) {
+			periods = append(periods, p)
+		}
+	}
+	return periods
+}
+
+func fullYear(y string) string {
+	if len(y) == 2 {
+		return "20" + y
+	}
+	return y
+}
+
+// pickValue returns the value a keyword ending at key refers to: the first
+// one after it, or the new one in "from $120 to $150".
+func pickValue(s string, key int, values []value) (value, bool) {
+	if len(values) == 0 {
+		return value{}, false
+	}
+	v := values[0]
+	if len(values) > 1 && strings.TrimSpace(s[v.end:values[1].start]) == "to" &&
+		strings.HasSuffix(strings.TrimSpace(strings.ToLower(s[:v.start])), "from") {
+		v = values[1]
+	}
+	if v.start-key > maxFactWindow {
+		return value{}, false
+	}
+	return v, true
+}
+
+// priceValues returns the prices in s from index from on, skipping
+// percentages.
+func priceValues(s string, from int) []value {
+	out := make([]value, 0)
+	for _, loc := range rexPrice.FindAllStringSubmatchIndex(s[from:], -1) {
+		if loc[8] >= 0 {
+			continue
+		}
+		m := sub(s[from:], loc)
+		v, err := strconv.ParseFloat(strings.ReplaceAll(m[2], ",", "")+m[3], 64)
+		if err != nil || v == 0 {
+			continue
+		}
+		out = append(out, value{v: v, currency: currencies[m[1]], start: from + loc[0], end: from + loc[1]})
+	}
+	return out
+}
+
+func epsValues(s string, from int) []value {
+	out := make([]value, 0)
+	for _, loc := range rexEPS.FindAllStringSubmatchIndex(s[from:], -1) {
+		if loc[8] >= 0 {
+			// growth rates and margins
+			continue
+		}
+		m := sub(s[from:], loc)
+		v, err := strconv.ParseFloat(m[3], 64)
+		if err != nil {
+			continue
+		}
+		if m[2] != "" {
+			// "(0.42)" or "-0.42" is a loss
+			v = -v
+		}
+		out = append(out, value{v: v, currency: currencies[m[1]], start: from + loc[0], end: from + loc[1]})
+	}
+	return out
+}
+
+func revenueValues(s string, from int) []value {
+	out := make([]value, 0)
+	for _, loc := range rexRevenue.FindAllStringSubmatchIndex(s[from:], -1) {
+		m := sub(s[from:], loc)
+		v, err := strconv.ParseFloat(strings.ReplaceAll(m[2], ",", ""), 64)
+		if err != nil {
+			continue
+		}
+		v *= scales[strings.ToLower(m[3])]
+		out = append(out, value{v: v, currency: currencies[m[1]], start: from + loc[0], end: from + loc[1]})
+	}
+	return out
+}
+
+// preferCurrency returns the values with a currency sign if there are any,
+// so "12-month price target of $150" reads 150.
+func preferCurrency(values []value) []value {
+	out := make([]value, 0, len(values))
+	for _, v := range values {
+		if v.currency != "" {
+			out = append(out, v)
+		}
+	}
+	if len(out) == 0 {
+		return values
+	}
+	return out
+}
+
+func titleCase(s string) string {
+	words := strings.Fields(s)
+	for i, w := range words {
+		words[i] = strings.ToUpper(w[:1]) + w[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+// sub returns the submatches of s at loc, "" for groups that did not match.
+func sub(s string, loc []int) []string {
+	out := make([]string, len(loc)/2)
+	for i := range out {
+		if loc[2*i] >= 0 {
+			out[i] = s[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return out
+}
@@ -0,0 +1,91 @@
+package enrich
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+func TestFactExtractor(t *testing.T) {
+	ref := NewReference([]Company{
+		{Symbol: "NVDA", Name: "NVIDIA Corp"},
+		{Symbol: "AMD", Name: "Advanced Micro Devices"},
+	})
+	e := NewFactExtractor(ref)
+	doc := &obj.Document{
+		Item:       &obj.Item{Id: "doc1"},
+		Symbols:    []string{"NVDA", "AMD"},
+		PostedDate: time.Date(2023, 5, 25, 0, 0, 0, 0, time.UTC),
+	}
+	text := `J.P. Morgan Equity Research
+Analyst: Harlan Sur
+NVIDIA Corp (NASDAQ: NVDA)
+Rating: Overweight
+We raise our price target from $400 to $500, implying 30% upside.
+We now estimate FY25 and FY26 EPS of $7.60 and $9.10, and see FY25 revenue of $41.5bn.
+Q2 FY24 EPS is expected at $2.07.
+We reiterate our Neutral rating on AMD with a 12-month price target of $110.
+Margins expanded 3.5% in the quarter.`
+
+	if err := e.Process(doc, text); err != nil {
+		t.Fatal(err)
+	}
+	type want struct {
+		kind, symbol, period, rating string
+		value                        float64
+	}
+	wants := []want{
+		{obj.FactRating, "NVDA", "", obj.RatingBuy, 0},
+		{obj.FactPriceTarget, "NVDA", "", "", 500},
+		{obj.FactEPS, "NVDA", "FY2025", "", 7.6},
+		{obj.FactEPS, "NVDA", "FY2026", "", 9.1},
+		{obj.FactRevenue, "NVDA", "FY2025", "", 41.5e9},
+		{obj.FactEPS, "NVDA", "Q2 2024", "", 2.07},
+		{obj.FactPriceTarget, "AMD", "", "", 110},
+		{obj.FactRating, "AMD", "", obj.RatingHold, 0},
+	}
+	if len(doc.Facts) != len(wants) {
+		t.Fatalf("facts = %+v", doc.Facts)
+	}
+	for _, w := range wants {
+		found := false
+		for _, f := range doc.Facts {
+			if f.Kind == w.kind && f.Symbol == w.symbol && f.Period == w.period && f.Rating == w.rating && f.Value == w.value {
+				found = true
+				if f.Firm != "J.P. Morgan" || f.Analyst != "Harlan Sur" || f.Document != "doc1" || !f.Date.Equal(doc.PostedDate) {
+					t.Errorf("fact attribution = %+v", f)
+				}
+				if f.Kind != obj.FactRating && f.Currency != "USD" {
+					t.Errorf("currency = %+v", f)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("missing %+v in %+v", w, doc.Facts)
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2023, 1, d, 0, 0, 0, 0, time.UTC) }
+	facts := []obj.Fact{
+		{Kind: obj.FactPriceTarget, Symbol: "NVDA", Value: 300, Currency: "USD", Firm: "UBS", Date: day(1)},
+		{Kind: obj.FactPriceTarget, Symbol: "NVDA", Value: 350, Currency: "USD", Firm: "UBS", Date: day(5)},
+		{Kind: obj.FactPriceTarget, Symbol: "NVDA", Value: 250, Currency: "USD", Firm: "Citi", Date: day(3)},
+		{Kind: obj.FactRating, Symbol: "NVDA", Rating: obj.RatingBuy, Firm: "UBS", Date: day(1)},
+		{Kind: obj.FactRating, Symbol: "NVDA", Rating: obj.RatingSell, Document: "a", Date: day(2)},
+		{Kind: obj.FactRating, Symbol: "NVDA", Rating: obj.RatingSell, Document: "b", Date: day(2)},
+	}
+	c := Summarize(facts)
+	if len(c) != 2 {
+		t.Fatalf("consensus = %+v", c)
+	}
+	pt, rating := c[0], c[1]
+	if pt.Kind != obj.FactPriceTarget || pt.Count != 2 || pt.Mean != 300 || pt.Low != 250 || pt.High != 350 || !pt.Updated.Equal(day(5)) {
+		t.Fatalf("price target consensus = %+v", pt)
+	}
+	if rating.Count != 3 || rating.Ratings[obj.RatingBuy] != 1 || rating.Ratings[obj.RatingSell] != 2 {
+		t.Fatalf("rating consensus = %+v", rating)
+	}
+}
@@ -0,0 +1,180 @@
+package enrich
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrReferenceFormat = errors.New("unrecognized symbol reference format")
+)
+
+// Company is a single entry of the symbol reference file.
+type Company struct {
+	Symbol   string
+	Name     string
+	Exchange string
+	Sector   string
+	CIK      string
+}
+
+// Reference resolves symbols and company names.
+type Reference struct {
+	bySymbol map[string]Company
+	byName   map[string]string // normalized name -> symbol
+	maxWords int
+}
+
+func NewReference(companies []Company) *Reference {
+	r := &Reference{
+		bySymbol: make(map[string]Company, len(companies)),
+		byName:   make(map[string]string, len(companies)),
+	}
+	for _, c := range companies {
+		c.Symbol = strings.ToUpper(strings.TrimSpace(c.Symbol))
+		if c.Symbol == "" {
+			continue
+		}
+		r.bySymbol[c.Symbol] = c
+		name := normalizeName(c.Name)
+		if len(name) < 4 {
+			continue
+		}
+		if _, ok := r.byName[name]; !ok {
+			r.byName[name] = c.Symbol
+		}
+		if n := len(strings.Fields(name)); n > r.maxWords {
+			r.maxWords = n
+		}
+	}
+	return r
+}
+
+// LoadReference reads a symbol reference file. CSV files need a header row
+// with at least "symbol" and "name" columns; "exchange", "sector" and "cik"
+// are optional. JSON files are either an array of Company or the SEC
+// company_tickers.json format.
+func LoadReference(file string) (*Reference, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".csv":
+		return readReferenceCSV(f)
+	case ".json":
+		return readReferenceJSON(f)
+	default:
+		return nil, ErrReferenceFormat
+	}
+}
+
+func readReferenceCSV(r io.Reader) (*Reference, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return NewReference(nil), nil
+	}
+	cols := make(map[string]int)
+	for i, h := range rows[0] {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	if _, ok := cols["symbol"]; !ok {
+		return nil, ErrReferenceFormat
+	}
+	get := func(row []string, col string) string {
+		if i, ok := cols[col]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	companies := make([]Company, 0, len(rows)-1)
+	for _, row := range rows[1:] {
+		companies = append(companies, Company{
+			Symbol:   get(row, "symbol"),
+			Name:     get(row, "name"),
+			Exchange: get(row, "exchange"),
+			Sector:   get(row, "sector"),
+			CIK:      get(row, "cik"),
+		})
+	}
+	return NewReference(companies), nil
+}
+
+func readReferenceJSON(r io.Reader) (*Reference, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var companies []Company
+	if err = json.Unmarshal(b, &companies); err == nil {
+		return NewReference(companies), nil
+	}
+	var sec map[string]struct {
+		CIK    int    `json:"cik_str"`
+		Ticker string `json:"ticker"`
+		Title  string `json:"title"`
+	}
+	if err = json.Unmarshal(b, &sec); err != nil {
+		return nil, ErrReferenceFormat
+	}
+	companies = make([]Company, 0, len(sec))
+	for _, v := range sec {
+		companies = append(companies, Company{Symbol: v.Ticker, Name: v.Title, CIK: strconv.Itoa(v.CIK)})
+	}
+	return NewReference(companies), nil
+}
+
+// Lookup returns the reference entry for symbol.
+func (r *Reference) Lookup(symbol string) (Company, bool) {
+	if r == nil {
+		return Company{}, false
+	}
+	c, ok := r.bySymbol[strings.ToUpper(symbol)]
+	return c, ok
+}
+
+// Has reports whether symbol is a known ticker. A nil Reference knows every
+// symbol.
+func (r *Reference) Has(symbol string) bool {
+	if r == nil {
+		return true
+	}
+	_, ok := r.bySymbol[strings.ToUpper(symbol)]
+	return ok
+}
+
+var nameSuffixes = map[string]bool{
+	"inc": true, "incorporated": true, "corp": true, "corporation": true, "co": true,
+	"company": true, "ltd": true, "limited": true, "plc": true, "llc": true, "lp": true,
+	"holdings": true, "holding": true, "group": true, "sa": true, "ag": true, "nv": true,
+	"se": true, "the": true, "class": true, "a": true, "b": true, "c": true, "com": true,
+}
+
+// normalizeName lowercases a company name and strips punctuation and legal
+// suffixes, so "Apple Inc." and "apple" compare equal.
+func normalizeName(name string) string {
+	words := nameWords(name)
+	for len(words) > 0 && nameSuffixes[words[len(words)-1]] {
+		words = words[:len(words)-1]
+	}
+	for len(words) > 0 && words[0] == "the" {
+		words = words[1:]
+	}
+	return strings.Join(words, " ")
+}
+
+func nameWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '&' || r > 127)
+	})
+}
@@ -0,0 +1,272 @@
+package enrich
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/mrod502/stockscraper/obj"
+	"github.com/mrod502/stockscraper/search"
+)
+
+const (
+	DefaultMinSectorScore = 3.0
+	maxSectors            = 3
+	primarySymbolWeight   = 5.0
+	symbolWeight          = 2.0
+	maxKeywordHits        = 5 // per keyword, so one repeated word can't decide the sector
+)
+
+// Taxonomy is a GICS style two level sector taxonomy.
+type Taxonomy struct {
+	Sectors []Sector
+}
+
+type Sector struct {
+	Code     string
+	Name     string
+	Keywords []string
+	Symbols  []string
+	Groups   []IndustryGroup
+}
+
+type IndustryGroup struct {
+	Code     string
+	Name     string
+	Keywords []string
+	Symbols  []string
+}
+
+// LoadTaxonomy reads a taxonomy from a JSON file shaped like Taxonomy, or from
+// a CSV file with the header
+//
+//	sector_code,sector,group_code,group,keywords,symbols
+//
+// where keywords and symbols are separated by semicolons.
+func LoadTaxonomy(file string) (*Taxonomy, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		var t Taxonomy
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		return &t, json.Unmarshal(b, &t)
+	case ".csv":
+		return readTaxonomyCSV(f)
+	default:
+		return nil, ErrReferenceFormat
+	}
+}
+
+func readTaxonomyCSV(r io.Reader) (*Taxonomy, error) {
+	rows, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	t := &Taxonomy{}
+	if len(rows) < 2 {
+		return t, nil
+	}
+	cols := make(map[string]int)
+	for i, h := range rows[0] {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	get := func(row []string, col string) string {
+		if i, ok := cols[col]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	list := func(s string) []string {
+		out := make([]string, 0)
+		for _, v := range strings.Split(s, ";") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+		return out
+	}
+	index := make(map[string]int)
+	for _, row := range rows[1:] {
+		name := get(row, "sector")
+		i, ok := index[name]
+		if !ok {
+			i = len(t.Sectors)
+			index[name] = i
+			t.Sectors = append(t.Sectors, Sector{Code: get(row, "sector_code"), Name: name})
+		}
+		if group := get(row, "group"); group != "" {
+			t.Sectors[i].Groups = append(t.Sectors[i].Groups, IndustryGroup{
+				Code:     get(row, "group_code"),
+				Name:     group,
+				Keywords: list(get(row, "keywords")),
+				Symbols:  list(get(row, "symbols")),
+			})
+		} else {
+			t.Sectors[i].Keywords = append(t.Sectors[i].Keywords, list(get(row, "keywords"))...)
+			t.Sectors[i].Symbols = append(t.Sectors[i].Symbols, list(get(row, "symbols"))...)
+		}
+	}
+	return t, nil
+}
+
+// node is a sector (group < 0) or one of its industry groups.
+type node struct {
+	sector, group int
+}
+
+// SectorClassifier assigns sectors from the symbols of a document and the
+// taxonomy keywords found in its text.
+type SectorClassifier struct {
+	t        *Taxonomy
+	minScore float64
+	symbols  map[string]node
+	phrases  map[string][]phrase // first stemmed term -> phrases starting with it
+}
+
+type phrase struct {
+	terms []string
+	n     node
+}
+
+func NewSectorClassifier(t *Taxonomy, ref *Reference, cfg Config) *SectorClassifier {
+	c := &SectorClassifier{
+		t:        t,
+		minScore: cfg.MinSectorScore,
+		symbols:  make(map[string]node),
+		phrases:  make(map[string][]phrase),
+	}
+	if c.minScore <= 0 {
+		c.minScore = DefaultMinSectorScore
+	}
+	addKeywords := func(keywords []string, n node) {
+		for _, k := range keywords {
+			if terms := search.Terms(k); len(terms) > 0 {
+				c.phrases[terms[0]] = append(c.phrases[terms[0]], phrase{terms: terms, n: n})
+			}
+		}
+	}
+	names := make(map[string]node)
+	for i, s := range t.Sectors {
+		n := node{sector: i, group: -1}
+		names[strings.ToLower(s.Name)], names[s.Code] = n, n
+		addKeywords(s.Keywords, n)
+		for _, sym := range s.Symbols {
+			c.symbols[strings.ToUpper(sym)] = n
+		}
+		for j, g := range s.Groups {
+			n := node{sector: i, group: j}
+			names[strings.ToLower(g.Name)], names[g.Code] = n, n
+			addKeywords(g.Keywords, n)
+			for _, sym := range g.Symbols {
+				c.symbols[strings.ToUpper(sym)] = n
+			}
+		}
+	}
+	// the symbol reference may carry a sector column naming a sector or group
+	if ref != nil {
+		for sym, co := range ref.bySymbol {
+			if _, ok := c.symbols[sym]; ok || co.Sector == "" {
+				continue
+			}
+			if n, ok := names[strings.ToLower(co.Sector)]; ok {
+				c.symbols[sym] = n
+			} else if n, ok := names[co.Sector]; ok {
+				c.symbols[sym] = n
+			}
+		}
+	}
+	return c
+}
+
+// Classify returns the names of the sectors, and of their best industry
+// group, that symbols and text relate to, most relevant first.
+func (c *SectorClassifier) Classify(symbols []string, text string) []string {
+	sectorScore := make([]float64, len(c.t.Sectors))
+	groupScore := make(map[node]float64)
+	add := func(n node, v float64) {
+		sectorScore[n.sector] += v
+		if n.group >= 0 {
+			groupScore[n] += v
+		}
+	}
+
+	for i, sym := range symbols {
+		if n, ok := c.symbols[strings.ToUpper(sym)]; ok {
+			if i == 0 {
+				add(n, primarySymbolWeight)
+			} else {
+				add(n, symbolWeight)
+			}
+		}
+	}
+
+	terms := search.Terms(text)
+	hits := make(map[*phrase]int)
+	for i, term := range terms {
+		for j := range c.phrases[term] {
+			p := &c.phrases[term][j]
+			if i+len(p.terms) <= len(terms) && equal(terms[i:i+len(p.terms)], p.terms) && hits[p] < maxKeywordHits {
+				hits[p]++
+				add(p.n, 1)
+			}
+		}
+	}
+
+	order := make([]int, 0, len(sectorScore))
+	for i, v := range sectorScore {
+		if v >= c.minScore {
+			order = append(order, i)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool { return sectorScore[order[a]] > sectorScore[order[b]] })
+	if len(order) > maxSectors {
+		order = order[:maxSectors]
+	}
+
+	out := make([]string, 0, len(order)*2)
+	for _, i := range order {
+		out = append(out, c.t.Sectors[i].Name)
+		best, bestScore := -1, 0.0
+		for n, v := range groupScore {
+			if n.sector == i && (v > bestScore || v == bestScore && n.group < best) {
+				best, bestScore = n.group, v
+			}
+		}
+		// a group can share its sector's name, e.g. Energy in GICS
+		if best >= 0 && !contains(out, c.t.Sectors[i].Groups[best].Name) {
+			out = append(out, c.t.Sectors[i].Groups[best].Name)
+		}
+	}
+	return out
+}
+
+// Process sets doc.Sectors. It should run after symbol extraction.
+func (c *SectorClassifier) Process(doc *obj.Document, text string) error {
+	if sectors := c.Classify(doc.Symbols, doc.Title+"\n"+text); len(sectors) > 0 {
+		doc.Sectors = sectors
+	}
+	return nil
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
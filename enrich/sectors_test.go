@@ -0,0 +1,31 @@
+package enrich
+
+import (
+	"testing"
+)
+
+func TestSectorClassifier(t *testing.T) {
+	tax := &Taxonomy{Sectors: []Sector{
+		{Code: "45", Name: "Information Technology", Groups: []IndustryGroup{
+			{Code: "4530", Name: "Semiconductors & Semiconductor Equipment", Keywords: []string{"semiconductor", "wafer", "foundry"}},
+			{Code: "4510", Name: "Software & Services", Keywords: []string{"software", "SaaS"}},
+		}},
+		{Code: "10", Name: "Energy", Keywords: []string{"oil", "natural gas", "drilling"}},
+	}}
+	ref := NewReference([]Company{{Symbol: "NVDA", Name: "NVIDIA Corp", Sector: "4530"}})
+	c := NewSectorClassifier(tax, ref, Config{})
+
+	sectors := c.Classify([]string{"NVDA"}, "Wafer supply at the foundry constrained semiconductor output; oil prices were flat.")
+	if len(sectors) != 2 || sectors[0] != "Information Technology" || sectors[1] != "Semiconductors & Semiconductor Equipment" {
+		t.Fatalf("unexpected sectors %v", sectors)
+	}
+
+	energy := NewSectorClassifier(&Taxonomy{Sectors: []Sector{
+		{Code: "10", Name: "Energy", Groups: []IndustryGroup{
+			{Code: "1010", Name: "Energy", Keywords: []string{"oil", "natural gas", "drilling"}},
+		}},
+	}}, nil, Config{})
+	if sectors = energy.Classify(nil, "Oil and natural gas drilling activity rose as oil prices climbed."); len(sectors) != 1 || sectors[0] != "Energy" {
+		t.Fatalf("sector and group of the same name = %v", sectors)
+	}
+}
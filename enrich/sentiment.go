@@ -0,0 +1,243 @@
+package enrich
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+// Word lists, named as in the Loughran-McDonald master dictionary.
+const (
+	ListPositive    = "positive"
+	ListNegative    = "negative"
+	ListUncertainty = "uncertainty"
+	ListLitigious   = "litigious"
+
+	negationWindow = 3 // words before a positive word a negation applies to
+)
+
+const (
+	inPositive uint8 = 1 << iota
+	inNegative
+	inUncertainty
+	inLitigious
+)
+
+var (
+	listBits = map[string]uint8{
+		ListPositive:    inPositive,
+		ListNegative:    inNegative,
+		ListUncertainty: inUncertainty,
+		ListLitigious:   inLitigious,
+	}
+	// negations turn a following positive word negative, e.g. "not improved".
+	negations = map[string]bool{
+		"no": true, "not": true, "none": true, "neither": true, "never": true, "nobody": true,
+	}
+)
+
+// Lexicon holds the sentiment word lists. Words are matched whole and
+// case-insensitively, without stemming, as the lists already spell out the
+// inflections.
+type Lexicon struct {
+	words map[string]uint8
+}
+
+// NewLexicon builds a lexicon from word lists keyed by ListPositive,
+// ListNegative, ListUncertainty and ListLitigious. Other lists are ignored.
+func NewLexicon(lists map[string][]string) *Lexicon {
+	l := &Lexicon{words: make(map[string]uint8)}
+	for name, words := range lists {
+		bit, ok := listBits[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		for _, w := range words {
+			if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+				l.words[w] |= bit
+			}
+		}
+	}
+	return l
+}
+
+// LoadLexicon reads word lists from a JSON object of lists, such as
+//
+//	{"positive": ["gain", ...], "negative": ["loss", ...]}
+//
+// or from the Loughran-McDonald master dictionary CSV, where a word belongs
+// to a list when its column holds the year it was added; zero or a negative
+// year (removed) means it does not.
+func LoadLexicon(file string) (*Lexicon, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		var lists map[string][]string
+		b, err := io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+		if err = json.Unmarshal(b, &lists); err != nil {
+			return nil, err
+		}
+		return NewLexicon(lists), nil
+	case ".csv":
+		return readLexiconCSV(f)
+	default:
+		return nil, ErrReferenceFormat
+	}
+}
+
+func readLexiconCSV(r io.Reader) (*Lexicon, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	l := &Lexicon{words: make(map[string]uint8)}
+	if len(rows) < 2 {
+		return l, nil
+	}
+	word := -1
+	cols := make(map[int]uint8)
+	for i, h := range rows[0] {
+		h = strings.ToLower(strings.TrimSpace(h))
+		if h == "word" {
+			word = i
+		} else if bit, ok := listBits[h]; ok {
+			cols[i] = bit
+		}
+	}
+	if word < 0 {
+		return nil, ErrReferenceFormat
+	}
+	for _, row := range rows[1:] {
+		if word >= len(row) {
+			continue
+		}
+		w := strings.ToLower(strings.TrimSpace(row[word]))
+		for i, bit := range cols {
+			if i >= len(row) {
+				continue
+			}
+			if year, err := strconv.Atoi(strings.TrimSpace(row[i])); err == nil && year > 0 {
+				l.words[w] |= bit
+			}
+		}
+	}
+	return l, nil
+}
+
+// SentimentScorer measures the tone of a document's text with a Lexicon.
+type SentimentScorer struct {
+	lex *Lexicon
+}
+
+func NewSentimentScorer(lex *Lexicon) *SentimentScorer {
+	return &SentimentScorer{lex: lex}
+}
+
+// Score counts the words of text found in each list. A positive word shortly
+// after a negation counts as negative.
+func (s *SentimentScorer) Score(text string) obj.Sentiment {
+	var (
+		sent                       obj.Sentiment
+		pos, neg, uncertain, litig int
+		lastNegation               = -negationWindow - 1
+	)
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && r != '\'' })
+	for _, w := range words {
+		w = strings.ToLower(strings.Trim(w, "'"))
+		if w == "" {
+			continue
+		}
+		if negations[w] {
+			lastNegation = sent.Words
+		}
+		bits := s.lex.words[w]
+		switch {
+		case bits&inPositive != 0 && sent.Words-lastNegation <= negationWindow:
+			neg++
+		case bits&inPositive != 0:
+			pos++
+		case bits&inNegative != 0:
+			neg++
+		}
+		if bits&inUncertainty != 0 {
+			uncertain++
+		}
+		if bits&inLitigious != 0 {
+			litig++
+		}
+		sent.Words++
+	}
+	if sent.Words == 0 {
+		return sent
+	}
+	n := float64(sent.Words)
+	sent.Positive, sent.Negative = float64(pos)/n, float64(neg)/n
+	sent.Uncertainty, sent.Litigious = float64(uncertain)/n, float64(litig)/n
+	if pos+neg > 0 {
+		sent.Tone = float64(pos-neg) / float64(pos+neg)
+	}
+	return sent
+}
+
+func (s *SentimentScorer) Process(doc *obj.Document, text string) error {
+	if sent := s.Score(text); sent.Words > 0 {
+		doc.Sentiment = &sent
+	}
+	return nil
+}
+
+// TonePoint is the sentiment of one document in a series, with the change
+// in tone since the previous document of the same type.
+type TonePoint struct {
+	Document  string
+	Title     string
+	Type      string
+	Date      time.Time
+	Sentiment obj.Sentiment
+	Change    *float64 `json:",omitempty"` // Tone minus the previous document's Tone; nil for the first
+}
+
+// ToneSeries orders the scored documents by date and compares each with the
+// previous one of its type, so a 10-K is measured against the prior 10-K
+// rather than the quarter in between.
+func ToneSeries(docs []*obj.Document) []TonePoint {
+	out := make([]TonePoint, 0, len(docs))
+	for _, d := range docs {
+		if d.Sentiment == nil {
+			continue
+		}
+		date := d.PostedDate
+		if date.IsZero() {
+			date = d.Fetched
+		}
+		out = append(out, TonePoint{Document: d.Id, Title: d.Title, Type: d.Type, Date: date, Sentiment: *d.Sentiment})
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Date.Before(out[j].Date) })
+	prev := make(map[string]float64)
+	for i, p := range out {
+		if tone, ok := prev[p.Type]; ok {
+			change := p.Sentiment.Tone - tone
+			out[i].Change = &change
+		}
+		prev[p.Type] = p.Sentiment.Tone
+	}
+	return out
+}
@@ -0,0 +1,88 @@
+package enrich
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+func TestLoadLexicon(t *testing.T) {
+	dir := t.TempDir()
+	csvFile := filepath.Join(dir, "lm.csv")
+	os.WriteFile(csvFile, []byte(`Word,Seq_num,Negative,Positive,Uncertainty,Litigious
+ABANDON,1,2009,0,0,0
+GAIN,2,0,2009,0,0
+UNCERTAIN,3,0,0,2009,0
+LITIGATION,4,2009,0,0,2009
+STRONG,5,0,-2020,0,0
+`), 0644)
+	jsonFile := filepath.Join(dir, "lists.json")
+	os.WriteFile(jsonFile, []byte(`{"Positive": ["gain"], "negative": ["abandon", "litigation"],
+		"uncertainty": ["uncertain"], "litigious": ["litigation"], "constraining": ["require"]}`), 0644)
+
+	for _, file := range []string{csvFile, jsonFile} {
+		lex, err := LoadLexicon(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := map[string]uint8{"abandon": inNegative, "gain": inPositive, "uncertain": inUncertainty, "litigation": inNegative | inLitigious}
+		if len(lex.words) != len(want) {
+			t.Fatalf("%s: words = %v", file, lex.words)
+		}
+		for w, bits := range want {
+			if lex.words[w] != bits {
+				t.Errorf("%s: %s = %b, want %b", file, w, lex.words[w], bits)
+			}
+		}
+	}
+}
+
+func TestSentimentScorer(t *testing.T) {
+	s := NewSentimentScorer(NewLexicon(map[string][]string{
+		ListPositive:    {"strong", "improved", "gain"},
+		ListNegative:    {"loss", "decline"},
+		ListUncertainty: {"may"},
+		ListLitigious:   {"lawsuit"},
+	}))
+	doc := &obj.Document{}
+	text := "Revenue was strong and margins improved. The lawsuit may cause a loss; results have not materially improved."
+	if err := s.Process(doc, text); err != nil {
+		t.Fatal(err)
+	}
+	got := doc.Sentiment
+	if got == nil || got.Words != 17 {
+		t.Fatalf("sentiment = %+v", got)
+	}
+	// strong, improved positive; loss and the negated improved negative
+	if got.Positive != 2.0/17 || got.Negative != 2.0/17 || got.Tone != 0 ||
+		got.Uncertainty != 1.0/17 || got.Litigious != 1.0/17 {
+		t.Fatalf("sentiment = %+v", got)
+	}
+	if s.Process(doc, ""); doc.Sentiment != got {
+		t.Fatal("empty text replaced the score")
+	}
+}
+
+func TestToneSeries(t *testing.T) {
+	day := func(m time.Month) time.Time { return time.Date(2023, m, 1, 0, 0, 0, 0, time.UTC) }
+	docs := []*obj.Document{
+		{Item: &obj.Item{Id: "k2"}, Type: obj.Type10K, PostedDate: day(12), Sentiment: &obj.Sentiment{Tone: -0.5}},
+		{Item: &obj.Item{Id: "q"}, Type: obj.Type10Q, PostedDate: day(6), Sentiment: &obj.Sentiment{Tone: 0.9}},
+		{Item: &obj.Item{Id: "k1"}, Type: obj.Type10K, PostedDate: day(1), Sentiment: &obj.Sentiment{Tone: 0.25}},
+		{Item: &obj.Item{Id: "unscored"}, Type: obj.Type10K, PostedDate: day(3)},
+	}
+	series := ToneSeries(docs)
+	if len(series) != 3 || series[0].Document != "k1" || series[1].Document != "q" || series[2].Document != "k2" {
+		t.Fatalf("series = %+v", series)
+	}
+	if series[0].Change != nil || series[1].Change != nil {
+		t.Fatalf("first of each type has a change: %+v", series)
+	}
+	if c := series[2].Change; c == nil || math.Abs(*c+0.75) > 1e-9 {
+		t.Fatalf("10-K change = %v", c)
+	}
+}
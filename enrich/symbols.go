@@ -0,0 +1,158 @@
+package enrich
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+const (
+	DefaultMinConfidence = 0.5
+	DefaultMaxSymbols    = 10
+)
+
+// Base confidence of a single mention, by how it was found.
+const (
+	confExchange = 0.95
+	confCashtag  = 0.8
+	confParen    = 0.6
+	confName     = 0.3 // below DefaultMinConfidence: names like Target or Block are also common words
+	confUnknown  = 0.5  // multiplier for tickers missing from the reference
+)
+
+var (
+	rexExchangeTicker = regexp.MustCompile(`\b(?:NASDAQ|Nasdaq|NYSE(?:\s?American|\s?Arca|ARCA)?|AMEX|OTC(?:QX|QB)?|TSXV?|LSE|ASX|HKEX|BATS|CBOE)\s*:\s*([A-Z]{1,5}(?:\.[A-Z])?)\b`)
+	rexCashtag        = regexp.MustCompile(`(?:^|[^\w$])\$([A-Z]{1,5}(?:\.[A-Z])?)\b`)
+	rexParenTicker    = regexp.MustCompile(`\(([A-Z]{1,5}(?:\.[A-Z])?)\)`)
+)
+
+type SymbolMatch struct {
+	Symbol     string
+	Confidence float64
+	Mentions   int
+}
+
+// SymbolExtractor finds ticker symbols in document text. Without a Reference
+// only cashtags and exchange-prefixed tickers are recognized.
+type SymbolExtractor struct {
+	ref           *Reference
+	minConfidence float64
+	maxSymbols    int
+}
+
+func NewSymbolExtractor(ref *Reference, cfg Config) *SymbolExtractor {
+	e := &SymbolExtractor{
+		ref:           ref,
+		minConfidence: cfg.MinConfidence,
+		maxSymbols:    cfg.MaxSymbols,
+	}
+	if e.minConfidence <= 0 {
+		e.minConfidence = DefaultMinConfidence
+	}
+	if e.maxSymbols <= 0 {
+		e.maxSymbols = DefaultMaxSymbols
+	}
+	return e
+}
+
+// Extract returns the symbols mentioned in text, most confident first.
+func (e *SymbolExtractor) Extract(text string) []SymbolMatch {
+	miss := make(map[string]float64) // probability every mention so far is wrong
+	count := make(map[string]int)
+	add := func(sym string, conf float64) {
+		if e.ref != nil && !e.ref.Has(sym) {
+			conf *= confUnknown
+		}
+		if _, ok := miss[sym]; !ok {
+			miss[sym] = 1
+		}
+		miss[sym] *= 1 - conf
+		count[sym]++
+	}
+
+	for _, m := range rexExchangeTicker.FindAllStringSubmatch(text, -1) {
+		add(m[1], confExchange)
+	}
+	for _, m := range rexCashtag.FindAllStringSubmatch(text, -1) {
+		add(m[1], confCashtag)
+	}
+	if e.ref != nil {
+		for _, m := range rexParenTicker.FindAllStringSubmatch(text, -1) {
+			if e.ref.Has(m[1]) {
+				add(m[1], confParen)
+			}
+		}
+		for _, sym := range e.ref.findNames(text) {
+			add(sym, confName)
+		}
+	}
+
+	matches := make([]SymbolMatch, 0, len(miss))
+	for sym, p := range miss {
+		if c := 1 - p; c >= e.minConfidence {
+			matches = append(matches, SymbolMatch{Symbol: sym, Confidence: c, Mentions: count[sym]})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Confidence == matches[j].Confidence {
+			return matches[i].Mentions > matches[j].Mentions
+		}
+		return matches[i].Confidence > matches[j].Confidence
+	})
+	if len(matches) > e.maxSymbols {
+		matches = matches[:e.maxSymbols]
+	}
+	return matches
+}
+
+// Process fills doc.Symbols, keeping any symbols already set first.
+func (e *SymbolExtractor) Process(doc *obj.Document, text string) error {
+	for _, m := range e.Extract(doc.Title + "\n" + text) {
+		if !contains(doc.Symbols, m.Symbol) {
+			doc.Symbols = append(doc.Symbols, m.Symbol)
+		}
+	}
+	return nil
+}
+
+// findNames returns the symbol of every company name occurring in text.
+// Names must start with a capital letter to avoid matching common words.
+func (r *Reference) findNames(text string) []string {
+	words := strings.FieldsFunc(text, func(c rune) bool {
+		return !(unicode.IsLetter(c) || unicode.IsDigit(c) || c == '&')
+	})
+	lower := make([]string, len(words))
+	for i, w := range words {
+		lower[i] = strings.ToLower(w)
+	}
+
+	found := make([]string, 0)
+	for i := 0; i < len(words); i++ {
+		if first := []rune(words[i])[0]; !unicode.IsUpper(first) {
+			continue
+		}
+		for n := r.maxWords; n > 0; n-- {
+			if i+n > len(words) {
+				continue
+			}
+			if sym, ok := r.byName[strings.Join(lower[i:i+n], " ")]; ok {
+				found = append(found, sym)
+				i += n - 1
+				break
+			}
+		}
+	}
+	return found
+}
+
+func contains(v []string, s string) bool {
+	for _, x := range v {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,55 @@
+package enrich
+
+import (
+	"testing"
+)
+
+func TestSymbolExtractor(t *testing.T) {
+	ref := NewReference([]Company{
+		{Symbol: "NVDA", Name: "NVIDIA Corp"},
+		{Symbol: "AAPL", Name: "Apple Inc."},
+		{Symbol: "MSFT", Name: "Microsoft Corporation"},
+	})
+	e := NewSymbolExtractor(ref, Config{})
+
+	text := `NVIDIA (NASDAQ: NVDA) guided above consensus. We also like $MSFT and
+Apple Inc. shares, while $XYZQ chatter is noise. An apple a day... Apple's
+services margin is the one to watch.`
+	matches := e.Extract(text)
+	if len(matches) < 2 || matches[0].Symbol != "NVDA" {
+		t.Fatalf("unexpected matches %+v", matches)
+	}
+	found := make(map[string]bool)
+	for _, m := range matches {
+		if m.Symbol == "XYZQ" {
+			t.Fatalf("unknown cashtag should fall below threshold: %+v", m)
+		}
+		found[m.Symbol] = true
+	}
+	for _, sym := range []string{"NVDA", "MSFT", "AAPL"} {
+		if !found[sym] {
+			t.Errorf("%s missing from %+v", sym, matches)
+		}
+	}
+}
+
+func TestSymbolExtractorCommonWords(t *testing.T) {
+	ref := NewReference([]Company{
+		{Symbol: "TGT", Name: "Target Corporation"},
+		{Symbol: "V", Name: "Visa Inc."},
+		{Symbol: "SNAP", Name: "Snap Inc."},
+		{Symbol: "SQ", Name: "Block, Inc."},
+	})
+	e := NewSymbolExtractor(ref, Config{})
+
+	text := `Target price raised to 140. Visa processing times for analysts are long,
+Snap judgments are risky and Block trades moved the close.`
+	if matches := e.Extract(text); len(matches) > 0 {
+		t.Fatalf("common words tagged as symbols: %+v", matches)
+	}
+	// backed by a cashtag or a second mention a name counts
+	matches := e.Extract(text + " Shares of Snap Inc. ($SNAP) rose, and Block said Block will report.")
+	if len(matches) != 2 || matches[0].Symbol != "SNAP" || matches[1].Symbol != "SQ" {
+		t.Fatalf("matches = %+v", matches)
+	}
+}
@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/signal"
+	"syscall"
 
 	"github.com/mrod502/stockscraper/api"
 	"github.com/mrod502/stockscraper/obj"
@@ -28,15 +30,11 @@ func main() {
 	}
 	obj.Setup(cfg.Obj)
 	defer r.Close()
-	go func() {
-		err = r.Serve()
-		if err != nil {
-			panic(err)
-		}
-	}()
 
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	<-c
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if err = r.Serve(ctx); err != nil {
+		fmt.Println(err.Error())
+	}
 	fmt.Println("bye")
 }
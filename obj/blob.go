@@ -0,0 +1,153 @@
+package obj
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path"
+	"strings"
+)
+
+// Document content is stored in blobs named by the sha256 of their bytes, so
+// the same file mirrored on several sites is stored once. Next to each blob a
+// refs file lists the ids of the documents pointing at it, and the blob is
+// deleted when the last of them lets go. The sources directory maps a
+// document id to the blob holding its current copy.
+
+func (d documentManager) blobsPath() string { return path.Join(d.baseDir, "blobs") }
+
+func (d documentManager) sourcesPath() string { return path.Join(d.baseDir, "sources") }
+
+// blobName returns the name of the blob holding b. It depends on the bytes
+// alone, so mirrors serving them under different content types share it; the
+// content type is kept on the Document and its Versions.
+func blobName(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func (d *documentManager) blobPath(blob string) string {
+	return path.Join(d.blobsPath(), blob[:2], blob)
+}
+
+func (d *documentManager) blobTextPath(blob string) string {
+	return d.blobPath(blob) + ".txt"
+}
+
+func (d *documentManager) blobTablesPath(blob string) string {
+	return d.blobPath(blob) + ".tables.json"
+}
+
+func (d *documentManager) refsPath(blob string) string {
+	return d.blobPath(blob) + ".refs"
+}
+
+func (d *documentManager) sourcePath(doc *Document) string {
+	return path.Join(d.sourcesPath(), doc.Id)
+}
+
+// blobOf returns the blob holding doc's current copy, or "" if it was stored
+// before blobs were used.
+func (d *documentManager) blobOf(doc *Document) string {
+	if doc.Blob != "" {
+		return doc.Blob
+	}
+	b, err := os.ReadFile(d.sourcePath(doc))
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+// putBlob stores b unless a blob with the same content exists, and adds a
+// reference to it from document id.
+func (d *documentManager) putBlob(b []byte, id string) (blob string, err error) {
+	blob = blobName(b)
+	d.blobL.Lock()
+	defer d.blobL.Unlock()
+	p := d.blobPath(blob)
+	if !fileExists(p) {
+		if err = os.MkdirAll(path.Dir(p), 0777); err != nil {
+			return
+		}
+		// write under another name first so a partial blob is never seen
+		if err = os.WriteFile(p+".tmp", b, 0666); err != nil {
+			return
+		}
+		if err = os.Rename(p+".tmp", p); err != nil {
+			return
+		}
+	}
+	return blob, d.addRef(blob, id)
+}
+
+// blobText returns the text already extracted from blob and the number of
+// tables found with it. ok is false if nothing was extracted yet.
+func (d *documentManager) blobText(blob string) (text string, tables int, ok bool) {
+	b, err := os.ReadFile(d.blobTextPath(blob))
+	if err != nil {
+		return "", 0, false
+	}
+	var t []json.RawMessage
+	if tb, err := os.ReadFile(d.blobTablesPath(blob)); err == nil && json.Unmarshal(tb, &t) == nil {
+		tables = len(t)
+	}
+	return string(b), tables, true
+}
+
+// releaseBlob drops the reference to blob from document id and deletes the
+// blob once nothing refers to it.
+func (d *documentManager) releaseBlob(blob, id string) error {
+	d.blobL.Lock()
+	defer d.blobL.Unlock()
+	ids, err := d.refs(blob)
+	if err != nil {
+		return err
+	}
+	keep := ids[:0]
+	for _, v := range ids {
+		if v != id {
+			keep = append(keep, v)
+		}
+	}
+	if len(keep) > 0 {
+		return d.writeRefs(blob, keep)
+	}
+	for _, p := range []string{d.blobPath(blob), d.blobTextPath(blob), d.blobTablesPath(blob), d.refsPath(blob)} {
+		if err = os.Remove(p); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// refs returns the ids of the documents referring to blob.
+func (d *documentManager) refs(blob string) ([]string, error) {
+	b, err := os.ReadFile(d.refsPath(blob))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(b)), nil
+}
+
+// addRef must be called with blobL held.
+func (d *documentManager) addRef(blob, id string) error {
+	ids, err := d.refs(blob)
+	if err != nil {
+		return err
+	}
+	for _, v := range ids {
+		if v == id {
+			return nil
+		}
+	}
+	return d.writeRefs(blob, append(ids, id))
+}
+
+func (d *documentManager) writeRefs(blob string, ids []string) error {
+	return os.WriteFile(d.refsPath(blob), []byte(strings.Join(ids, "\n")+"\n"), 0666)
+}
@@ -0,0 +1,217 @@
+package obj
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DiffLine is one line of a line diff. Op is ' ' for a line both texts share,
+// '-' for a line only in the old text and '+' for a line only in the new one.
+type DiffLine struct {
+	Op   byte
+	Text string
+}
+
+// DiffLines returns the shortest edit script turning a into b, using the
+// linear space variant of Myers' algorithm: it finds the middle snake of an
+// optimal path and recurses on either side of it, so memory stays
+// proportional to the input however many lines differ. Within a change,
+// removed lines come before added ones.
+func DiffLines(a, b []string) []DiffLine {
+	size := len(a) + len(b) + 3
+	d := &differ{a: a, b: b, vf: make([]int, size+1), vb: make([]int, size+1),
+		out: make([]DiffLine, 0, len(a)+len(b))}
+	d.diff(0, len(a), 0, len(b))
+	// order each run of changes as removals then additions
+	for i := 0; i < len(d.out); {
+		if d.out[i].Op == ' ' {
+			i++
+			continue
+		}
+		j := i
+		for j < len(d.out) && d.out[j].Op != ' ' {
+			j++
+		}
+		sort.SliceStable(d.out[i:j], func(x, y int) bool { return d.out[i+x].Op == '-' && d.out[i+y].Op == '+' })
+		i = j
+	}
+	return d.out
+}
+
+type differ struct {
+	a, b   []string
+	vf, vb []int // furthest reaching x per diagonal, forward and backward
+	out    []DiffLine
+}
+
+// diff appends the edit script turning a[a0:a1] into b[b0:b1].
+func (d *differ) diff(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.out = append(d.out, DiffLine{' ', d.a[a0]})
+		a0++
+		b0++
+	}
+	suffix := 0
+	for a1 > a0 && b1 > b0 && d.a[a1-1] == d.b[b1-1] {
+		a1--
+		b1--
+		suffix++
+	}
+	switch {
+	case a0 == a1:
+		for _, l := range d.b[b0:b1] {
+			d.out = append(d.out, DiffLine{'+', l})
+		}
+	case b0 == b1:
+		for _, l := range d.a[a0:a1] {
+			d.out = append(d.out, DiffLine{'-', l})
+		}
+	default:
+		// with the common ends stripped at least two edits remain, so
+		// both sides of the snake are smaller than the whole
+		x, y, u, v := d.middleSnake(a0, a1, b0, b1)
+		d.diff(a0, x, b0, y)
+		for _, l := range d.a[x:u] {
+			d.out = append(d.out, DiffLine{' ', l})
+		}
+		d.diff(u, a1, v, b1)
+	}
+	for _, l := range d.a[a1 : a1+suffix] {
+		d.out = append(d.out, DiffLine{' ', l})
+	}
+}
+
+// middleSnake runs the search from both ends of a[a0:a1] and b[b0:b1] until
+// the paths overlap, and returns the snake where they meet, from (x, y) to
+// (u, v).
+func (d *differ) middleSnake(a0, a1, b0, b1 int) (x, y, u, v int) {
+	n, m := a1-a0, b1-b0
+	delta := n - m
+	odd := delta&1 != 0
+	max := (n + m + 1) / 2
+	off := max + 1
+	vf, vb := d.vf, d.vb
+	vf[off+1], vb[off+1] = 0, 0
+	for D := 0; D <= max; D++ {
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || k != D && vf[off+k-1] < vf[off+k+1] {
+				x = vf[off+k+1]
+			} else {
+				x = vf[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && d.a[a0+x] == d.b[b0+y] {
+				x++
+				y++
+			}
+			vf[off+k] = x
+			// backward diagonal delta-k, as of step D-1
+			if kb := delta - k; odd && kb >= -(D-1) && kb <= D-1 && x+vb[off+kb] >= n {
+				return a0 + sx, b0 + sy, a0 + x, b0 + y
+			}
+		}
+		for k := -D; k <= D; k += 2 {
+			var x int
+			if k == -D || k != D && vb[off+k-1] < vb[off+k+1] {
+				x = vb[off+k+1]
+			} else {
+				x = vb[off+k-1] + 1
+			}
+			y := x - k
+			sx, sy := x, y
+			for x < n && y < m && d.a[a1-1-x] == d.b[b1-1-y] {
+				x++
+				y++
+			}
+			vb[off+k] = x
+			if kf := delta - k; !odd && kf >= -D && kf <= D && vf[off+kf]+x >= n {
+				return a1 - x, b1 - y, a1 - sx, b1 - sy
+			}
+		}
+	}
+	panic("obj: diff paths did not meet")
+}
+
+// UnifiedDiff formats the line diff of two texts in unified diff format with
+// context lines around each change. It returns "" if the texts are equal.
+func UnifiedDiff(fromName, toName, a, b string, context int) string {
+	lines := DiffLines(splitLines(a), splitLines(b))
+
+	// line numbers of each diff line in a and b, 1 based
+	type pos struct{ a, b int }
+	at := make([]pos, len(lines))
+	na, nb := 1, 1
+	for i, l := range lines {
+		at[i] = pos{na, nb}
+		if l.Op != '+' {
+			na++
+		}
+		if l.Op != '-' {
+			nb++
+		}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(lines); {
+		if lines[i].Op == ' ' {
+			i++
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// extend the hunk while the next change is within two contexts
+		end := i
+		for j := i; j < len(lines) && j <= end+2*context; j++ {
+			if lines[j].Op != ' ' {
+				end = j
+			}
+		}
+		stop := end + context + 1
+		if stop > len(lines) {
+			stop = len(lines)
+		}
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", fromName, toName)
+		}
+		var countA, countB int
+		for _, l := range lines[start:stop] {
+			if l.Op != '+' {
+				countA++
+			}
+			if l.Op != '-' {
+				countB++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(at[start].a, countA), hunkRange(at[start].b, countB))
+		for _, l := range lines[start:stop] {
+			sb.WriteByte(l.Op)
+			sb.WriteString(l.Text)
+			sb.WriteByte('\n')
+		}
+		i = stop
+	}
+	return sb.String()
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		// an empty range names the line before it
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if count == 1 {
+		return fmt.Sprint(start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
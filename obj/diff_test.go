@@ -0,0 +1,115 @@
+package obj
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// checkDiff verifies that lines rebuild a and b and returns its edit count.
+func checkDiff(t *testing.T, a, b []string, lines []DiffLine) int {
+	t.Helper()
+	var edits int
+	var gotA, gotB []string
+	for _, l := range lines {
+		if l.Op != ' ' {
+			edits++
+		}
+		if l.Op != '+' {
+			gotA = append(gotA, l.Text)
+		}
+		if l.Op != '-' {
+			gotB = append(gotB, l.Text)
+		}
+	}
+	if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+		t.Fatalf("diff does not rebuild its inputs: %v %v", gotA, gotB)
+	}
+	return edits
+}
+
+func TestDiffLines(t *testing.T) {
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+	if edits := checkDiff(t, a, b, DiffLines(a, b)); edits != 5 {
+		t.Fatalf("%d edits, want 5", edits)
+	}
+
+	// compare with the edit distance from a longest common subsequence table
+	r := rand.New(rand.NewSource(1))
+	random := func() []string {
+		v := make([]string, r.Intn(12))
+		for i := range v {
+			v[i] = string(rune('a' + r.Intn(3)))
+		}
+		return v
+	}
+	for i := 0; i < 500; i++ {
+		a, b := random(), random()
+		lcs := make([][]int, len(a)+1)
+		for x := range lcs {
+			lcs[x] = make([]int, len(b)+1)
+		}
+		for x := len(a) - 1; x >= 0; x-- {
+			for y := len(b) - 1; y >= 0; y-- {
+				switch {
+				case a[x] == b[y]:
+					lcs[x][y] = lcs[x+1][y+1] + 1
+				case lcs[x+1][y] > lcs[x][y+1]:
+					lcs[x][y] = lcs[x+1][y]
+				default:
+					lcs[x][y] = lcs[x][y+1]
+				}
+			}
+		}
+		if edits, want := checkDiff(t, a, b, DiffLines(a, b)), len(a)+len(b)-2*lcs[0][0]; edits != want {
+			t.Fatalf("%v -> %v: %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+func TestDiffLinesLarge(t *testing.T) {
+	a := make([]string, 20000)
+	b := make([]string, len(a))
+	for i := range a {
+		a[i] = fmt.Sprintf("line %d", i)
+		b[i] = a[i]
+		if i%4 == 0 {
+			b[i] = fmt.Sprintf("changed %d", i)
+		}
+	}
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	lines := DiffLines(a, b)
+	runtime.ReadMemStats(&after)
+	if edits := checkDiff(t, a, b, lines); edits != 10000 {
+		t.Fatalf("%d edits, want 10000", edits)
+	}
+	if alloc := after.TotalAlloc - before.TotalAlloc; alloc > 32<<20 {
+		t.Fatalf("diff allocated %d MB", alloc>>20)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "Revenue grew 10%\nMargins were flat\n1\n2\n3\n4\n5\n6\n7\n8\nGuidance unchanged\n"
+	after := "Revenue grew 12%\nMargins were flat\n1\n2\n3\n4\n5\n6\n7\n8\nGuidance raised\n"
+	want := `--- v1
++++ v2
+@@ -1,2 +1,2 @@
+-Revenue grew 10%
++Revenue grew 12%
+ Margins were flat
+@@ -10,2 +10,2 @@
+ 8
+-Guidance unchanged
++Guidance raised
+`
+	if got := UnifiedDiff("v1", "v2", before, after, 1); got != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := UnifiedDiff("v1", "v2", before, before, 3); got != "" {
+		t.Fatalf("equal texts gave %q", got)
+	}
+}
@@ -1,6 +1,7 @@
 package obj
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/base64"
 	"errors"
@@ -17,6 +18,7 @@ var (
 	ErrNoExtension   = errors.New("no file extension")
 	ErrFilenameParse = errors.New("unable to parse uri into filename")
 	ErrFileType      = errors.New("incorrect filetype")
+	errNotModified   = errors.New("not modified")
 )
 var (
 	rexSrc = regexp.MustCompile(`src='([^']+)'`)
@@ -34,27 +36,118 @@ func NewDocument(r *http.Response) (d *Document) {
 type Document struct {
 	*Item
 	Title       string    `msgpack:"tit,omitempty"`
+	Author      string    `msgpack:"aut,omitempty"`
 	Symbols     []string  `msgpack:"sym,omitempty"` // Stock or crypto symbols mentioned in the article
 	Sectors     []string  `msgpack:"sct,omitempty"` // Sectors of industry / finance this document mentions / relates to
 	Source      string    `msgpack:"src,omitempty"` // The URL of this document
 	ContentType string    `msgpack:"ctt,omitempty"` // the content type (pdf,etc)
 	Type        string    `msgpack:"typ,omitempty"` // Financial statement, analysis, blog post, etc...
 	PostedDate  time.Time `msgpack:"pdate,omitempty"`
+	Engines     []string  `msgpack:"eng,omitempty"` // search engines that returned this document
+	LinkText    string    `msgpack:"lnk,omitempty"` // text of the link a crawler followed here, the title if none is found
+
+	ETag         string     `msgpack:"etag,omitempty"` // validators from the last fetch, sent when refreshing
+	LastModified string     `msgpack:"lmod,omitempty"`
+	Fetched      time.Time  `msgpack:"fetched,omitempty"` // last time the source was checked
+	Hash         string     `msgpack:"hash,omitempty"`    // signature of the stored content
+	Versions     []Version  `msgpack:"ver,omitempty"`     // earlier copies, oldest first
+	Blob         string     `msgpack:"blob,omitempty"`    // content-addressed file holding the stored content
+	Tables       int        `msgpack:"tbl,omitempty"`     // number of tables found in the content
+	Facts        []Fact     `msgpack:"fct,omitempty"`     // figures and ratings stated in the content
+	Sentiment    *Sentiment `msgpack:"snt,omitempty"`     // tone of the extracted text
+}
+
+// Version describes an earlier copy of a document that was replaced by a
+// refresh. The copy itself is kept in the file store.
+type Version struct {
+	Number       int
+	ContentType  string
+	ETag         string
+	LastModified string
+	Fetched      time.Time
+	Hash         string
+	Blob         string
 }
 
 func (d *Document) Create() error {
 	d.Id = GetSignature([]byte(d.Source))
-	if fileExists(docMgr.genPath(d)) {
+	if fileExists(docMgr.sourcePath(d)) || fileExists(docMgr.genPath(d)) {
 		return os.ErrExist
 	}
-	docMgr.saveChan <- d
-	return nil
+	return docMgr.queue(d)
+}
+
+// Refresh fetches the document again with a conditional request and stores
+// the new content if it changed, keeping the old copy as a version.
+func (d *Document) Refresh(ctx context.Context) (changed bool, err error) {
+	if docMgr == nil {
+		return false, ErrClosed
+	}
+	return docMgr.refresh(ctx, d)
+}
+
+// History returns every version of the document, oldest first, ending with
+// the current one.
+func (d *Document) History() []Version {
+	return append(append([]Version{}, d.Versions...), Version{
+		Number:       len(d.Versions) + 1,
+		ContentType:  d.ContentType,
+		ETag:         d.ETag,
+		LastModified: d.LastModified,
+		Fetched:      d.Fetched,
+		Hash:         d.Hash,
+		Blob:         d.Blob,
+	})
+}
+
+// LoadVersion returns the content and extracted text of version n, numbered
+// from 1. The highest number is the current copy.
+func (d *Document) LoadVersion(n int) (b []byte, text string, err error) {
+	if docMgr == nil {
+		return nil, "", ErrClosed
+	}
+	return docMgr.loadVersion(d, n)
+}
+
+// Content returns the stored content of the current copy of the document.
+func (d *Document) Content() ([]byte, error) {
+	if docMgr == nil {
+		return nil, ErrClosed
+	}
+	return docMgr.load(d)
+}
+
+// LoadTables returns the tables found in the current copy of the document.
+func (d *Document) LoadTables() ([]Table, error) {
+	if docMgr == nil {
+		return nil, ErrClosed
+	}
+	return docMgr.loadTables(d)
+}
+
+// Mirrors returns the ids of the documents sharing a stored blob with this
+// one, including its own. Some may only share it through an earlier version.
+func (d *Document) Mirrors() ([]string, error) {
+	if docMgr == nil {
+		return nil, ErrClosed
+	}
+	blob := docMgr.blobOf(d)
+	if blob == "" {
+		return []string{d.Id}, nil
+	}
+	return docMgr.refs(blob)
 }
 
 func (d *Document) Destroy() error {
 	return docMgr.remove(d)
 }
 
+// Text returns the extracted plain text of the document.
+func (d *Document) Text() (string, error) {
+	b, err := docMgr.loadText(d)
+	return string(b), err
+}
+
 func (d *Document) Provide(w http.ResponseWriter) error {
 	b, err := docMgr.load(d)
 	if err != nil {
@@ -74,25 +167,48 @@ func Includes(s string, v []string) bool {
 	return false
 }
 
-func (d *Document) doRequest() (res *http.Response, err error) {
-	req := generateBrowserRequest(d.Source)
+// doRequest gets the document's source. A conditional request sends the
+// stored validators and returns errNotModified if the server answers 304.
+func (d *Document) doRequest(ctx context.Context, conditional bool) (res *http.Response, err error) {
+	req := generateBrowserRequest(ctx, d.Source)
 	if req == nil {
 		return nil, errors.New("nil request")
 	}
+	if conditional {
+		if d.ETag != "" {
+			req.Header.Set("if-none-match", d.ETag)
+		}
+		if d.LastModified != "" {
+			req.Header.Set("if-modified-since", d.LastModified)
+		}
+	}
 	res, err = http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, err
 	}
+	if res.StatusCode == http.StatusNotModified {
+		res.Body.Close()
+		return nil, errNotModified
+	}
 	d.ContentType = res.Header.Get("content-type")
+	d.ETag = res.Header.Get("etag")
+	d.LastModified = res.Header.Get("last-modified")
 	return
 }
 
-func (d *Document) retrieve() ([]byte, error) {
-	res, err := d.doRequest()
+func (d *Document) retrieve(ctx context.Context) ([]byte, error) {
+	return d.fetch(ctx, false)
+}
+
+// fetch gets the document's content and records when it was fetched.
+func (d *Document) fetch(ctx context.Context, conditional bool) ([]byte, error) {
+	d.Fetched = time.Now()
+	res, err := d.doRequest(ctx, conditional)
 	if err != nil {
 		fmt.Println(err.Error())
 		return nil, err
 	}
+	defer res.Body.Close()
 	fmt.Println("doc: got - ", res.Request.URL.EscapedPath())
 	b, err := io.ReadAll(res.Body)
 	if err != nil {
@@ -102,10 +218,11 @@ func (d *Document) retrieve() ([]byte, error) {
 		if m := rexSrc.FindStringSubmatch(string(b)); len(m) == 2 {
 			if strings.Contains(m[1], "pdf") {
 				d.Source = m[1]
-				res, err := d.doRequest()
+				res, err := d.doRequest(ctx, false)
 				if err != nil {
 					return nil, err
 				}
+				defer res.Body.Close()
 				return io.ReadAll(res.Body)
 			}
 		}
@@ -114,10 +231,10 @@ func (d *Document) retrieve() ([]byte, error) {
 	return b, err
 }
 
-func generateBrowserRequest(uri string) *http.Request {
+func generateBrowserRequest(ctx context.Context, uri string) *http.Request {
 	var req *http.Request
 	var err error
-	req, err = http.NewRequest("GET", uri, nil)
+	req, err = http.NewRequestWithContext(ctx, "GET", uri, nil)
 	if err != nil {
 		fmt.Println("generateBrowserRequest", uri, err)
 		return req
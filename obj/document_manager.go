@@ -1,21 +1,52 @@
 package obj
 
 import (
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path"
-	"strings"
 	"sync"
-
-	"code.sajari.com/docconv"
 )
 
 var (
 	docMgr         *documentManager
 	ErrUnsupported = errors.New("unsupported filetype")
+	ErrClosed      = errors.New("document manager is shut down")
+	ErrNoVersion   = errors.New("no such version")
+)
+
+var (
+	stages  []Stage
+	stagesL = &sync.RWMutex{}
 )
 
+// AddStage appends s to the stages run after every document save. Stages run
+// in the order they were added.
+func AddStage(s Stage) {
+	stagesL.Lock()
+	defer stagesL.Unlock()
+	stages = append(stages, s)
+}
+
+// runStages runs every stage over doc. A failed stage is logged and the rest
+// still run, so the last one, which stores the document, always sees it. The
+// first error is returned.
+func runStages(doc *Document, text string) (err error) {
+	stagesL.RLock()
+	defer stagesL.RUnlock()
+	for _, s := range stages {
+		if serr := s.Process(doc, text); serr != nil {
+			fmt.Println("STAGE:", doc.Id, serr.Error())
+			if err == nil {
+				err = serr
+			}
+		}
+	}
+	return err
+}
+
 type Config struct {
 	DBPath        string `yaml:"db_path"`
 	FileStorePath string `yaml:"file_store_path"`
@@ -30,21 +61,97 @@ func Setup(cfg Config) {
 
 }
 
+// Shutdown stops accepting documents and waits for the ones already queued to
+// be saved. If ctx expires first, saves in flight are aborted and the
+// documents that were not saved are returned so they can be queued again
+// later.
+func Shutdown(ctx context.Context) ([]*Document, error) {
+	if docMgr == nil {
+		return nil, nil
+	}
+	return docMgr.shutdown(ctx)
+}
+
 type documentManager struct {
 	baseDir  string
 	l        *sync.RWMutex
+	blobL    *sync.Mutex // guards blob refs
 	saveChan chan *Document
+
+	ctx     context.Context // canceled to abort saves when shutdown runs out of time
+	cancel  context.CancelFunc
+	closed  bool // guarded by sendL, set once saveChan is no longer read
+	sendL   *sync.RWMutex
+	stop    chan struct{}
+	stopped chan struct{}
+	unsaved []*Document
 }
 
 func (d *documentManager) saveProcessor() {
+	defer close(d.stopped)
 	for {
-		err := d.save(<-d.saveChan)
-		if err != nil {
-			fmt.Println("SAVE:", err.Error())
+		select {
+		case doc := <-d.saveChan:
+			d.saveQueued(doc)
+		case <-d.stop:
+			for {
+				select {
+				case doc := <-d.saveChan:
+					d.saveQueued(doc)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (d *documentManager) saveQueued(doc *Document) {
+	if d.ctx.Err() != nil {
+		d.unsaved = append(d.unsaved, doc)
+		return
+	}
+	if err := d.save(d.ctx, doc); err != nil {
+		fmt.Println("SAVE:", err.Error())
+		if d.ctx.Err() != nil {
+			d.unsaved = append(d.unsaved, doc)
 		}
 	}
 }
 
+// queue hands doc to the save processor.
+func (d *documentManager) queue(doc *Document) error {
+	d.sendL.RLock()
+	defer d.sendL.RUnlock()
+	if d.closed {
+		return ErrClosed
+	}
+	d.saveChan <- doc
+	return nil
+}
+
+func (d *documentManager) shutdown(ctx context.Context) ([]*Document, error) {
+	d.sendL.Lock()
+	if d.closed {
+		d.sendL.Unlock()
+		return nil, ErrClosed
+	}
+	d.closed = true
+	d.sendL.Unlock()
+	close(d.stop)
+
+	var err error
+	select {
+	case <-d.stopped:
+	case <-ctx.Done():
+		err = ctx.Err()
+		d.cancel()
+		<-d.stopped
+	}
+	d.cancel()
+	return d.unsaved, err
+}
+
 func (d documentManager) txtPath() string {
 	return path.Join(d.baseDir, "text")
 }
@@ -54,8 +161,19 @@ func (d documentManager) pdfPath() string {
 	return path.Join(d.baseDir, "pdf")
 }
 
+func (d documentManager) versionsPath() string { return path.Join(d.baseDir, "versions") }
+
 func NewDocumentManager(baseDir string) (d *documentManager, err error) {
-	d = &documentManager{baseDir: baseDir, l: &sync.RWMutex{}, saveChan: make(chan *Document, 512)}
+	d = &documentManager{
+		baseDir:  baseDir,
+		l:        &sync.RWMutex{},
+		blobL:    &sync.Mutex{},
+		saveChan: make(chan *Document, 512),
+		sendL:    &sync.RWMutex{},
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+	d.ctx, d.cancel = context.WithCancel(context.Background())
 	if err = os.Mkdir(baseDir, 0777); err != nil && !os.IsExist(err) {
 		return
 	}
@@ -65,57 +183,204 @@ func NewDocumentManager(baseDir string) (d *documentManager, err error) {
 	if err = os.Mkdir(d.pdfPath(), 0777); err != nil && !os.IsExist(err) {
 		return
 	}
+	if err = os.Mkdir(d.versionsPath(), 0777); err != nil && !os.IsExist(err) {
+		return
+	}
+	if err = os.Mkdir(d.blobsPath(), 0777); err != nil && !os.IsExist(err) {
+		return
+	}
+	if err = os.Mkdir(d.sourcesPath(), 0777); err != nil && !os.IsExist(err) {
+		return
+	}
 	err = nil
 	go d.saveProcessor()
 	return
 }
 
-func (d *documentManager) save(doc *Document) error {
-	b, err := doc.retrieve()
+func (d *documentManager) save(ctx context.Context, doc *Document) error {
+	b, err := doc.retrieve(ctx)
 	if err != nil {
 		return err
 	}
-	f, err := os.Create(d.genPath(doc))
+	return d.store(doc, b)
+}
+
+// store writes b as the content of doc, extracts its text and runs the stages.
+// Content already stored for another document is shared with it. The stages
+// run even if the text could not be extracted, so that the document is
+// recorded once its content is on disk.
+func (d *documentManager) store(doc *Document, b []byte) error {
+	doc.Hash = GetSignature(b)
+	blob, err := d.putBlob(b, doc.Id)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
-	_, err = f.Write(b)
+	doc.Blob = blob
+	if err = os.WriteFile(d.sourcePath(doc), []byte(blob), 0666); err != nil {
+		return err
+	}
+
+	text, err := d.saveText(doc, b)
+	if serr := runStages(doc, text); err == nil {
+		err = serr
+	}
+	return err
+}
 
+// refresh fetches doc with a conditional request. When the content changed
+// the old copy is kept as a version and the new one stored.
+func (d *documentManager) refresh(ctx context.Context, doc *Document) (bool, error) {
+	d.l.Lock()
+	defer d.l.Unlock()
+
+	prev := *doc
+	if prev.Hash == "" {
+		// stored before hashes were recorded
+		if b, err := os.ReadFile(d.contentPath(&prev)); err == nil {
+			prev.Hash = GetSignature(b)
+		}
+	}
+	b, err := doc.fetch(ctx, true)
+	if errors.Is(err, errNotModified) {
+		return false, nil
+	}
 	if err != nil {
-		return err
+		return false, err
+	}
+	if GetSignature(b) == prev.Hash {
+		doc.Hash = prev.Hash
+		return false, nil
 	}
-	if strings.Contains(doc.ContentType, "text/") {
-		return nil
+
+	v := Version{
+		Number:       len(doc.Versions) + 1,
+		ContentType:  prev.ContentType,
+		ETag:         prev.ETag,
+		LastModified: prev.LastModified,
+		Fetched:      prev.Fetched,
+		Hash:         prev.Hash,
+		Blob:         d.blobOf(&prev),
+	}
+	if v.Blob != "" {
+		// the old blob stays referenced by this document
+		doc.Versions = append(doc.Versions, v)
+	} else if old := d.genPath(&prev); fileExists(old) {
+		// stored before blobs, move the copy to the versions directory
+		if err = os.Rename(old, d.versionPath(doc, v)); err != nil {
+			return false, err
+		}
+		// text/plain documents are their own text file and were moved above
+		if text := d.textPath(&prev); fileExists(text) {
+			if err = os.Rename(text, d.versionTextPath(doc, v)); err != nil {
+				return false, err
+			}
+		}
+		doc.Versions = append(doc.Versions, v)
 	}
+	return true, d.store(doc, b)
+}
 
-	return d.saveText(doc)
+func (d *documentManager) versionPath(doc *Document, v Version) string {
+	return path.Join(d.versionsPath(), fmt.Sprintf("%s.v%d%s", doc.Id, v.Number, path.Ext(d.genPath(&Document{Item: doc.Item, ContentType: v.ContentType}))))
 }
 
-func (d *documentManager) remove(doc *Document) error {
+func (d *documentManager) versionTextPath(doc *Document, v Version) string {
+	return path.Join(d.versionsPath(), fmt.Sprintf("%s.v%d.txt", doc.Id, v.Number))
+}
+
+// loadVersion returns the content of version n of doc and its text, which is
+// empty if none was extracted.
+func (d *documentManager) loadVersion(doc *Document, n int) (b []byte, text string, err error) {
+	var t []byte
+	switch {
+	case n == len(doc.Versions)+1:
+		if b, err = d.load(doc); err != nil {
+			return
+		}
+		t, err = d.loadText(doc)
+	case n < 1 || n > len(doc.Versions):
+		return nil, "", ErrNoVersion
+	default:
+		v := doc.Versions[n-1]
+		if v.Blob != "" {
+			if b, err = os.ReadFile(d.blobPath(v.Blob)); err != nil {
+				return
+			}
+			t, err = os.ReadFile(d.blobTextPath(v.Blob))
+			break
+		}
+		if b, err = os.ReadFile(d.versionPath(doc, v)); err != nil {
+			return
+		}
+		t, err = os.ReadFile(d.versionTextPath(doc, v))
+	}
+	if os.IsNotExist(err) {
+		// no text could be extracted from this version
+		err = nil
+	}
+	return b, string(t), err
+}
 
+// remove drops doc's references to the blobs of its current copy and its
+// versions.
+func (d *documentManager) remove(doc *Document) error {
+	d.l.Lock()
+	defer d.l.Unlock()
+	seen := make(map[string]bool)
+	blobs := []string{d.blobOf(doc)}
+	for _, v := range doc.Versions {
+		blobs = append(blobs, v.Blob)
+	}
+	for _, blob := range blobs {
+		if blob == "" || seen[blob] {
+			continue
+		}
+		seen[blob] = true
+		if err := d.releaseBlob(blob, doc.Id); err != nil {
+			return err
+		}
+	}
+	if err := os.Remove(d.sourcePath(doc)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
 	return nil
 }
 
 func (d *documentManager) load(doc *Document) ([]byte, error) {
-	if b, err := os.ReadFile(d.genPath(doc)); err == nil {
+	if b, err := os.ReadFile(d.contentPath(doc)); err == nil {
 		return b, err
 	}
-	err := d.save(doc)
+	err := d.save(d.ctx, doc)
 	if err != nil {
 		return nil, err
 	}
-	return os.ReadFile(d.genPath(doc))
+	return os.ReadFile(d.contentPath(doc))
 }
 
 func (d *documentManager) loadFile(doc *Document) (*os.File, error) {
-	return os.Open(d.genPath(doc))
+	return os.Open(d.contentPath(doc))
 }
 
 func (d *documentManager) loadText(doc *Document) ([]byte, error) {
-	return os.ReadFile(path.Join(d.txtPath(), doc.Id, ".txt"))
+	return os.ReadFile(d.textPath(doc))
+}
+
+// contentPath returns the file holding doc's current copy.
+func (d *documentManager) contentPath(doc *Document) string {
+	if blob := d.blobOf(doc); blob != "" {
+		return d.blobPath(blob)
+	}
+	return d.genPath(doc)
+}
+
+func (d *documentManager) textPath(doc *Document) string {
+	if blob := d.blobOf(doc); blob != "" {
+		return d.blobTextPath(blob)
+	}
+	return path.Join(d.txtPath(), doc.Id+".txt")
 }
 
+// genPath is where a document was stored before content went into blobs.
 func (d *documentManager) genPath(doc *Document) string {
 	switch doc.ContentType {
 	case "text/plain":
@@ -131,27 +396,49 @@ func (d *documentManager) genPath(doc *Document) string {
 	}
 }
 
-func (d *documentManager) saveText(doc *Document) error {
-	if strings.Contains(doc.ContentType, "text/") {
-		return nil
+// saveText extracts the text, metadata and tables of doc, writes the text and
+// tables next to its blob and returns the text. b holds the raw bytes of the
+// document. A blob shared with a mirror keeps the text first extracted from
+// it, so all its documents read the same text even when served under
+// different content types, and one served with a type that can't be
+// extracted still gets it.
+func (d *documentManager) saveText(doc *Document, b []byte) (string, error) {
+	x, err := extract(doc, b)
+	text, tables, ok := d.blobText(doc.Blob)
+	doc.applyMeta(x)
+	if err != nil && !ok {
+		return "", err
 	}
-	if doc.ContentType != "application/pdf" {
-		return ErrUnsupported
+	if ok {
+		doc.Tables = tables
+		return text, nil
 	}
-	_, err := d.loadFile(doc)
-	if err != nil {
-		return err
+	doc.Tables = len(x.Tables)
+	if len(x.Tables) > 0 {
+		tables, err := json.Marshal(x.Tables)
+		if err != nil {
+			return "", err
+		}
+		if err = os.WriteFile(d.blobTablesPath(doc.Blob), tables, 0666); err != nil {
+			return "", err
+		}
 	}
-	res, err := docconv.ConvertPath(d.genPath(doc))
-	if err != nil {
-		return err
+	return x.Text, os.WriteFile(d.blobTextPath(doc.Blob), []byte(x.Text), 0666)
+}
+
+// loadTables returns the tables found in doc's current copy.
+func (d *documentManager) loadTables(doc *Document) ([]Table, error) {
+	tables := make([]Table, 0)
+	blob := d.blobOf(doc)
+	if blob == "" {
+		return tables, nil
+	}
+	b, err := os.ReadFile(d.blobTablesPath(blob))
+	if os.IsNotExist(err) {
+		return tables, nil
 	}
-	pth := path.Join(d.txtPath(), doc.Id+".txt")
-	f, err := os.Create(pth)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = f.WriteString(res.Body)
-
-	return err
+	return tables, json.Unmarshal(b, &tables)
 }
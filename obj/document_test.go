@@ -0,0 +1,222 @@
+package obj
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestRefresh(t *testing.T) {
+	content, etag := "first draft", `"v1"`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("if-none-match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("content-type", "text/plain")
+		w.Header().Set("etag", etag)
+		w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	Setup(Config{FileStorePath: t.TempDir()})
+	doc := &Document{Item: NewItem(TDocument), Source: srv.URL + "/deck.txt"}
+	doc.Id = GetSignature([]byte(doc.Source))
+	if err := docMgr.save(context.Background(), doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.ETag != etag || doc.Fetched.IsZero() || doc.Hash == "" {
+		t.Fatalf("validators not recorded: %+v", doc)
+	}
+
+	if changed, err := doc.Refresh(context.Background()); err != nil || changed {
+		t.Fatalf("unchanged refresh = %t, %v", changed, err)
+	}
+
+	// same content under a new etag is not a new version
+	etag = `"v2"`
+	if changed, err := doc.Refresh(context.Background()); err != nil || changed || doc.ETag != etag {
+		t.Fatalf("refresh = %t, %v, etag %s", changed, err, doc.ETag)
+	}
+
+	content, etag = "final draft", `"v3"`
+	changed, err := doc.Refresh(context.Background())
+	if err != nil || !changed {
+		t.Fatalf("changed refresh = %t, %v", changed, err)
+	}
+	if len(doc.Versions) != 1 || doc.Versions[0].ETag != `"v2"` {
+		t.Fatalf("versions = %+v", doc.Versions)
+	}
+	b, err := os.ReadFile(docMgr.blobPath(doc.Versions[0].Blob))
+	if err != nil || string(b) != "first draft" {
+		t.Fatalf("old version = %q, %v", b, err)
+	}
+	if text, _ := os.ReadFile(docMgr.blobPath(doc.Blob)); string(text) != "final draft" {
+		t.Fatalf("current = %q", text)
+	}
+
+	if h := doc.History(); len(h) != 2 || h[1].Number != 2 || h[1].ETag != etag {
+		t.Fatalf("history = %+v", h)
+	}
+	if _, text, err := doc.LoadVersion(1); err != nil || text != "first draft" {
+		t.Fatalf("version 1 = %q, %v", text, err)
+	}
+	if _, text, err := doc.LoadVersion(2); err != nil || text != "final draft" {
+		t.Fatalf("version 2 = %q, %v", text, err)
+	}
+	if _, _, err := doc.LoadVersion(3); err != ErrNoVersion {
+		t.Fatalf("version 3 err = %v", err)
+	}
+}
+
+func TestMirrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "text/plain")
+		if r.URL.Path == "/other.txt" {
+			w.Write([]byte("something else"))
+			return
+		}
+		w.Write([]byte("annual report"))
+	}))
+	defer srv.Close()
+
+	Setup(Config{FileStorePath: t.TempDir()})
+	docs := make([]*Document, 0, 3)
+	for _, p := range []string{"/a/report.txt", "/b/report.txt", "/other.txt"} {
+		doc := &Document{Item: NewItem(TDocument), Source: srv.URL + p}
+		doc.Id = GetSignature([]byte(doc.Source))
+		if err := docMgr.save(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+		docs = append(docs, doc)
+	}
+	a, b, other := docs[0], docs[1], docs[2]
+	if a.Blob == "" || a.Blob != b.Blob || a.Blob == other.Blob {
+		t.Fatalf("blobs = %s, %s, %s", a.Blob, b.Blob, other.Blob)
+	}
+	if ids, err := a.Mirrors(); err != nil || len(ids) != 2 || ids[0] != a.Id || ids[1] != b.Id {
+		t.Fatalf("mirrors = %v, %v", ids, err)
+	}
+	if text, err := b.Text(); err != nil || text != "annual report" {
+		t.Fatalf("text = %q, %v", text, err)
+	}
+	if err := (&Document{Item: &Item{Id: a.Id}, Source: a.Source}).Create(); err != os.ErrExist {
+		t.Fatalf("create stored document = %v", err)
+	}
+
+	if err := a.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := b.Mirrors(); len(ids) != 1 || ids[0] != b.Id {
+		t.Fatalf("mirrors after destroy = %v", ids)
+	}
+	if !fileExists(docMgr.blobPath(b.Blob)) {
+		t.Fatal("shared blob removed while still referenced")
+	}
+	if err := b.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(docMgr.blobPath(b.Blob)) {
+		t.Fatal("unreferenced blob kept")
+	}
+}
+
+func TestMirrorContentTypes(t *testing.T) {
+	const rtf = `{\rtf1\ansi{\info{\title Annual Report}}Revenue grew 12%.\par}`
+	types := map[string]string{
+		"/a/report.rtf": "application/rtf",
+		"/b/download":   "application/octet-stream",
+		"/c/report":     "text/plain",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", types[r.URL.Path])
+		w.Write([]byte(rtf))
+	}))
+	defer srv.Close()
+
+	Setup(Config{FileStorePath: t.TempDir()})
+	paths := []string{"/a/report.rtf", "/b/download", "/c/report"}
+	docs := make([]*Document, 0, len(paths))
+	for _, p := range paths {
+		doc := &Document{Item: NewItem(TDocument), Source: srv.URL + p}
+		doc.Id = GetSignature([]byte(doc.Source))
+		if err := docMgr.save(context.Background(), doc); err != nil {
+			t.Fatalf("%s: %v", p, err)
+		}
+		docs = append(docs, doc)
+	}
+	for _, doc := range docs[1:] {
+		if doc.Blob != docs[0].Blob {
+			t.Fatalf("blobs = %s, %s", docs[0].Blob, doc.Blob)
+		}
+	}
+	if ids, err := docs[2].Mirrors(); err != nil || len(ids) != 3 {
+		t.Fatalf("mirrors = %v, %v", ids, err)
+	}
+	for i, doc := range docs {
+		if doc.ContentType != types[paths[i]] {
+			t.Errorf("content type = %q", doc.ContentType)
+		}
+		if text, err := doc.Text(); err != nil || text != "Revenue grew 12%." {
+			t.Errorf("%s text = %q, %v", doc.Source, text, err)
+		}
+	}
+}
+
+func TestStagesRunOnFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "application/zip")
+		w.Write([]byte("PK\x03\x04"))
+	}))
+	defer srv.Close()
+
+	saved := stages
+	t.Cleanup(func() { stages = saved })
+	errStage := errors.New("stage failed")
+	var stored *Document
+	stages = []Stage{
+		StageFunc(func(*Document, string) error { return errStage }),
+		StageFunc(func(doc *Document, text string) error {
+			stored = doc
+			return nil
+		}),
+	}
+
+	Setup(Config{FileStorePath: t.TempDir()})
+	doc := &Document{Item: NewItem(TDocument), Source: srv.URL + "/archive.zip"}
+	doc.Id = GetSignature([]byte(doc.Source))
+	if err := docMgr.save(context.Background(), doc); err != ErrUnsupported {
+		t.Fatalf("save = %v", err)
+	}
+	if stored != doc || doc.Blob == "" {
+		t.Fatalf("last stage did not see the saved document: %+v", stored)
+	}
+}
+
+func TestLinkTextTitle(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/notes.txt" {
+			w.Header().Set("content-type", "text/plain")
+			w.Write([]byte("call notes"))
+			return
+		}
+		w.Header().Set("content-type", "text/html")
+		w.Write([]byte("<html><head><title>Q3 2023 Shareholder Letter</title></head><body><p>Revenue grew.</p></body></html>"))
+	}))
+	defer srv.Close()
+
+	Setup(Config{FileStorePath: t.TempDir()})
+	for path, want := range map[string]string{"/letter.html": "Q3 2023 Shareholder Letter", "/notes.txt": "here"} {
+		doc := &Document{Item: NewItem(TDocument), Source: srv.URL + path, LinkText: "here"}
+		doc.Id = GetSignature([]byte(doc.Source))
+		if err := docMgr.save(context.Background(), doc); err != nil {
+			t.Fatal(err)
+		}
+		if doc.Title != want {
+			t.Errorf("%s: title = %q, want %q", path, doc.Title, want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package obj
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+	"os/exec"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"code.sajari.com/docconv"
+	"golang.org/x/net/html/charset"
+)
+
+const (
+	mimeDocx = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	mimeXlsx = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	mimePptx = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+)
+
+// Extracted is the text of a document and the metadata and tables found in
+// it. Fields that could not be found are left empty.
+type Extracted struct {
+	Text    string
+	Title   string
+	Author  string
+	Created time.Time
+	Tables  []Table
+}
+
+// Extractor pulls the text out of one kind of document. contentType is the
+// full content type the document was served with, parameters included.
+type Extractor interface {
+	Extract(b []byte, contentType string) (Extracted, error)
+}
+
+type ExtractorFunc func(b []byte, contentType string) (Extracted, error)
+
+func (f ExtractorFunc) Extract(b []byte, contentType string) (Extracted, error) {
+	return f(b, contentType)
+}
+
+var (
+	extractors = map[string]Extractor{
+		"text/plain":            ExtractorFunc(extractPlain),
+		"text/html":             ExtractorFunc(extractHTML),
+		"application/xhtml+xml": ExtractorFunc(extractHTML),
+		"application/pdf":       ExtractorFunc(extractPDF),
+		mimeDocx:                ExtractorFunc(extractDocx),
+		mimeXlsx:                ExtractorFunc(extractXlsx),
+		mimePptx:                ExtractorFunc(extractPptx),
+		"application/rtf":       ExtractorFunc(extractRTF),
+		"text/rtf":              ExtractorFunc(extractRTF),
+		"application/xml":       ExtractorFunc(extractXML),
+		"text/xml":              ExtractorFunc(extractXML),
+		"application/xbrl+xml":  ExtractorFunc(extractXML),
+	}
+	extractorsL = &sync.RWMutex{}
+
+	// extTypes is used for documents served without a useful content type,
+	// e.g. application/octet-stream.
+	extTypes = map[string]string{
+		".txt":  "text/plain",
+		".htm":  "text/html",
+		".html": "text/html",
+		".pdf":  "application/pdf",
+		".docx": mimeDocx,
+		".xlsx": mimeXlsx,
+		".pptx": mimePptx,
+		".rtf":  "application/rtf",
+		".xml":  "application/xml",
+	}
+)
+
+// RegisterExtractor sets the extractor used for documents of mimeType,
+// replacing any already registered for it.
+func RegisterExtractor(mimeType string, e Extractor) {
+	extractorsL.Lock()
+	defer extractorsL.Unlock()
+	extractors[strings.ToLower(mimeType)] = e
+}
+
+// mediaType returns contentType without its parameters, lowercased.
+func mediaType(contentType string) string {
+	if t, _, err := mime.ParseMediaType(contentType); err == nil {
+		return t
+	}
+	t, _, _ := strings.Cut(contentType, ";")
+	return strings.ToLower(strings.TrimSpace(t))
+}
+
+// extractorFor returns the extractor for doc's content type, falling back to
+// the extension of its source URL.
+func extractorFor(doc *Document) (Extractor, bool) {
+	extractorsL.RLock()
+	defer extractorsL.RUnlock()
+	if e, ok := extractors[mediaType(doc.ContentType)]; ok {
+		return e, true
+	}
+	src := doc.Source
+	if i := strings.IndexAny(src, "?#"); i >= 0 {
+		src = src[:i]
+	}
+	e, ok := extractors[extTypes[strings.ToLower(path.Ext(src))]]
+	return e, ok
+}
+
+// extract runs the extractor for doc over b and normalizes the text.
+func extract(doc *Document, b []byte) (Extracted, error) {
+	e, ok := extractorFor(doc)
+	if !ok {
+		if doc.isText() {
+			return Extracted{}, nil
+		}
+		return Extracted{}, ErrUnsupported
+	}
+	x, err := e.Extract(b, doc.ContentType)
+	if err != nil {
+		return Extracted{}, err
+	}
+	x.Text = normalizeText(x.Text)
+	x.Title = strings.Join(strings.Fields(x.Title), " ")
+	x.Author = strings.Join(strings.Fields(x.Author), " ")
+	return x, nil
+}
+
+// applyMeta fills in the fields of doc that are still empty from what was
+// found in its content.
+func (d *Document) applyMeta(x Extracted) {
+	if d.Title == "" {
+		d.Title = x.Title
+	}
+	if d.Title == "" {
+		d.Title = d.LinkText
+	}
+	if d.Author == "" {
+		d.Author = x.Author
+	}
+	if d.PostedDate.IsZero() {
+		d.PostedDate = x.Created
+	}
+}
+
+var rexBlankLines = regexp.MustCompile(`\n{3,}`)
+
+// normalizeText returns s as valid UTF-8 with unix line endings, no trailing
+// spaces and at most one blank line in a row.
+func normalizeText(s string) string {
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	s = strings.NewReplacer("\r\n", "\n", "\r", "\n", "\u00a0", " ", "\x00", "").Replace(s)
+	lines := strings.Split(s, "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t\f\v")
+	}
+	s = rexBlankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n")
+	return strings.TrimSpace(s)
+}
+
+// decodeText converts b to UTF-8 using the charset from contentType or, if
+// there is none, one sniffed from the content.
+func decodeText(b []byte, contentType string) []byte {
+	r, err := charset.NewReader(bytes.NewReader(b), contentType)
+	if err != nil {
+		return b
+	}
+	out, err := io.ReadAll(r)
+	if err != nil {
+		return b
+	}
+	return out
+}
+
+func extractPlain(b []byte, contentType string) (Extracted, error) {
+	return Extracted{Text: string(decodeText(b, contentType))}, nil
+}
+
+// extractXML returns the character data of an XML document, one element's
+// text per line. Markup and attributes are dropped.
+func extractXML(b []byte, _ string) (Extracted, error) {
+	var sb strings.Builder
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.CharsetReader = charset.NewReaderLabel
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return Extracted{Text: sb.String()}, nil
+		}
+		if err != nil {
+			return Extracted{}, err
+		}
+		if cd, ok := tok.(xml.CharData); ok {
+			if s := strings.TrimSpace(string(cd)); s != "" {
+				sb.WriteString(s)
+				sb.WriteByte('\n')
+			}
+		}
+	}
+}
+
+func extractPDF(b []byte, _ string) (Extracted, error) {
+	text, meta, err := docconv.ConvertPDF(bytes.NewReader(b))
+	if err != nil {
+		return Extracted{}, err
+	}
+	x := Extracted{Text: text, Title: meta["Title"], Author: meta["Author"]}
+	if sec, err := strconv.ParseInt(meta["CreatedDate"], 10, 64); err == nil {
+		x.Created = time.Unix(sec, 0).UTC()
+	}
+	// the plain text loses the columns, tables are read from the page layout
+	if layout, err := pdfLayout(b); err == nil {
+		x.Tables = layoutTables(layout)
+	}
+	return x, nil
+}
+
+// pdfLayout returns the text of a pdf laid out as it is on the page, with
+// pages separated by form feeds.
+func pdfLayout(b []byte) (string, error) {
+	f, err := docconv.NewLocalFile(bytes.NewReader(b))
+	if err != nil {
+		return "", err
+	}
+	defer f.Done()
+	out, err := exec.Command("pdftotext", "-q", "-layout", "-enc", "UTF-8", "-eol", "unix", f.Name(), "-").Output()
+	return string(out), err
+}
+
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	time.RFC1123,
+	time.RFC1123Z,
+	"January 2, 2006",
+}
+
+// parseDate returns the time in s, or the zero time if it is not in a layout
+// documents commonly use.
+func parseDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,210 @@
+package obj
+
+import (
+	"bytes"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// minContentText is how much text an <article> or <main> element needs for
+// it to be taken as the page's content instead of the whole body.
+const minContentText = 200
+
+var (
+	// skipElements never hold a page's content.
+	skipElements = map[atom.Atom]bool{
+		atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Template: true,
+		atom.Svg: true, atom.Iframe: true, atom.Nav: true, atom.Header: true,
+		atom.Footer: true, atom.Aside: true, atom.Form: true, atom.Button: true,
+		atom.Select: true, atom.Head: true,
+	}
+	// boilerplateNames are the class and id words of page furniture.
+	boilerplateNames = []string{
+		"nav", "menu", "sidebar", "footer", "header", "cookie", "banner",
+		"breadcrumb", "share", "social", "related", "comment", "advert", "promo",
+		"newsletter", "subscribe", "popup", "modal",
+	}
+	// blockElements end a line of text.
+	blockElements = map[atom.Atom]bool{
+		atom.P: true, atom.Div: true, atom.Br: true, atom.Li: true, atom.Tr: true,
+		atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true,
+		atom.H6: true, atom.Blockquote: true, atom.Pre: true, atom.Section: true,
+		atom.Article: true, atom.Table: true, atom.Ul: true, atom.Ol: true,
+		atom.Dd: true, atom.Dt: true, atom.Figcaption: true, atom.Hr: true,
+	}
+	authorMeta = []string{"author", "article:author", "dc.creator", "byl"}
+	dateMeta   = []string{
+		"article:published_time", "datepublished", "date", "pubdate",
+		"dc.date", "dc.date.issued", "publish-date", "sailthru.date",
+		"time", // the first <time datetime> on the page
+	}
+	titleMeta = []string{"og:title", "twitter:title", "dc.title", "title"}
+)
+
+// extractHTML returns the main content of an html page, leaving out scripts,
+// navigation and other page furniture, along with the title, author and
+// publication date from its head.
+func extractHTML(b []byte, contentType string) (Extracted, error) {
+	root, err := html.Parse(bytes.NewReader(decodeText(b, contentType)))
+	if err != nil {
+		return Extracted{}, err
+	}
+	var (
+		x    Extracted
+		meta = make(map[string]string)
+		body *html.Node
+		main *html.Node
+		h1   string
+		walk func(n *html.Node)
+	)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.DataAtom {
+			case atom.Title:
+				if _, ok := meta["title"]; !ok {
+					meta["title"] = nodeText(n)
+				}
+			case atom.Meta:
+				key := strings.ToLower(attrOf(n, "name") + attrOf(n, "property") + attrOf(n, "itemprop"))
+				if _, ok := meta[key]; !ok && key != "" {
+					meta[key] = attrOf(n, "content")
+				}
+			case atom.Body:
+				body = n
+			case atom.Table:
+				if t, ok := htmlTable(n); ok {
+					x.Tables = append(x.Tables, t)
+				}
+			case atom.Article, atom.Main:
+				if main == nil {
+					main = n
+				}
+			case atom.H1:
+				if h1 == "" {
+					h1 = nodeText(n)
+				}
+			case atom.Time:
+				if _, ok := meta["time"]; !ok && attrOf(n, "datetime") != "" {
+					meta["time"] = attrOf(n, "datetime")
+				}
+			default:
+				if strings.EqualFold(attrOf(n, "role"), "main") && main == nil {
+					main = n
+				}
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+
+	for _, k := range titleMeta {
+		if x.Title == "" {
+			x.Title = meta[k]
+		}
+	}
+	if x.Title == "" {
+		x.Title = h1
+	}
+	for _, k := range authorMeta {
+		if x.Author == "" {
+			x.Author = meta[k]
+		}
+	}
+	for _, k := range dateMeta {
+		if x.Created.IsZero() && meta[k] != "" {
+			x.Created = parseDate(meta[k])
+		}
+	}
+
+	var sb strings.Builder
+	if main != nil {
+		writeContent(&sb, main, true)
+	}
+	if len(strings.TrimSpace(sb.String())) < minContentText {
+		sb.Reset()
+		if body == nil {
+			body = root
+		}
+		writeContent(&sb, body, true)
+	}
+	x.Text = sb.String()
+	return x, nil
+}
+
+// writeContent writes the visible text under n, a line per block element.
+// The root itself is never skipped for its class names.
+func writeContent(sb *strings.Builder, n *html.Node, root bool) {
+	switch n.Type {
+	case html.TextNode:
+		if t := strings.Join(strings.Fields(n.Data), " "); t != "" {
+			if sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+				sb.WriteByte(' ')
+			}
+			sb.WriteString(t)
+		}
+		return
+	case html.ElementNode:
+		if skipElements[n.DataAtom] || !root && isBoilerplate(n) {
+			return
+		}
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		writeContent(sb, c, false)
+	}
+	if n.Type == html.ElementNode && blockElements[n.DataAtom] && sb.Len() > 0 && !strings.HasSuffix(sb.String(), "\n") {
+		sb.WriteByte('\n')
+	}
+}
+
+func isBoilerplate(n *html.Node) bool {
+	if strings.EqualFold(attrOf(n, "aria-hidden"), "true") || hasAttr(n, "hidden") {
+		return true
+	}
+	names := strings.ToLower(attrOf(n, "class") + " " + attrOf(n, "id"))
+	for _, w := range strings.FieldsFunc(names, func(r rune) bool { return r == ' ' || r == '-' || r == '_' }) {
+		for _, b := range boilerplateNames {
+			if w == b {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func nodeText(n *html.Node) string {
+	var sb strings.Builder
+	var walk func(n *html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			sb.WriteString(n.Data)
+			sb.WriteByte(' ')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return strings.Join(strings.Fields(sb.String()), " ")
+}
+
+func attrOf(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return strings.TrimSpace(a.Val)
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,233 @@
+package obj
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"code.sajari.com/docconv"
+)
+
+// coreProperties is docProps/core.xml, the metadata of an Office Open XML
+// document.
+type coreProperties struct {
+	Title   string `xml:"title"`
+	Creator string `xml:"creator"`
+	Created string `xml:"created"`
+}
+
+// officeMeta returns the title, author and creation date of an Office Open XML
+// document. A document without core properties has none.
+func officeMeta(zr *zip.Reader) (x Extracted, err error) {
+	f, err := zr.Open("docProps/core.xml")
+	if err != nil {
+		return x, nil
+	}
+	defer f.Close()
+	var props coreProperties
+	if err = xml.NewDecoder(f).Decode(&props); err != nil {
+		return
+	}
+	return Extracted{Title: props.Title, Author: props.Creator, Created: parseDate(props.Created)}, nil
+}
+
+func extractDocx(b []byte, _ string) (Extracted, error) {
+	return extractOffice(b, docconv.ConvertDocx)
+}
+
+func extractPptx(b []byte, _ string) (Extracted, error) {
+	return extractOffice(b, docconv.ConvertPptx)
+}
+
+func extractXlsx(b []byte, _ string) (Extracted, error) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return Extracted{}, err
+	}
+	x, err := officeMeta(zr)
+	if err != nil {
+		return x, err
+	}
+	x.Text, x.Tables, err = xlsxText(zr)
+	return x, err
+}
+
+func extractOffice(b []byte, convert func(io.Reader) (string, map[string]string, error)) (Extracted, error) {
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return Extracted{}, err
+	}
+	x, err := officeMeta(zr)
+	if err != nil {
+		return x, err
+	}
+	x.Text, _, err = convert(bytes.NewReader(b))
+	return x, err
+}
+
+// xlsxText returns the cells of every worksheet, a line per row with the
+// cells separated by tabs and a blank line between sheets, and the sheets
+// holding figures as tables.
+func xlsxText(zr *zip.Reader) (string, []Table, error) {
+	shared, err := xlsxSharedStrings(zr)
+	if err != nil {
+		return "", nil, err
+	}
+	sheets := make([]*zip.File, 0)
+	for _, f := range zr.File {
+		if dir, name := path.Split(f.Name); dir == "xl/worksheets/" && strings.HasSuffix(name, ".xml") {
+			sheets = append(sheets, f)
+		}
+	}
+	// sheet2.xml before sheet10.xml
+	sort.Slice(sheets, func(i, j int) bool {
+		a, b := sheets[i].Name, sheets[j].Name
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+
+	var (
+		sb     strings.Builder
+		tables []Table
+	)
+	for _, f := range sheets {
+		rc, err := f.Open()
+		if err != nil {
+			return "", nil, err
+		}
+		rows, err := xlsxSheet(rc, shared)
+		rc.Close()
+		if err != nil {
+			return "", nil, err
+		}
+		for _, r := range rows {
+			sb.WriteString(strings.Join(r, "\t"))
+			sb.WriteByte('\n')
+		}
+		sb.WriteByte('\n')
+		if rows = padRows(rows); looksTabular(rows) {
+			tables = append(tables, Table{Rows: rows})
+		}
+	}
+	return sb.String(), tables, nil
+}
+
+func xlsxSharedStrings(zr *zip.Reader) ([]string, error) {
+	f, err := zr.Open("xl/sharedStrings.xml")
+	if err != nil {
+		// a workbook with no text cells has none
+		return nil, nil
+	}
+	defer f.Close()
+
+	strs := make([]string, 0)
+	var (
+		sb        strings.Builder
+		inText    bool
+		phonetics int
+	)
+	dec := xml.NewDecoder(f)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return strs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				sb.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				// pronunciation hints, not part of the text
+				phonetics++
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, sb.String())
+			case "t":
+				inText = false
+			case "rPh":
+				phonetics--
+			}
+		case xml.CharData:
+			if inText && phonetics == 0 {
+				sb.Write(t)
+			}
+		}
+	}
+}
+
+// xlsxSheet returns the rows of a worksheet that have values, without the
+// empty cells at their end.
+func xlsxSheet(r io.Reader, shared []string) (rows [][]string, err error) {
+	var (
+		row      []string
+		cellType string
+		value    strings.Builder
+		inValue  bool
+	)
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row = make([]string, 0)
+			case "c":
+				cellType = ""
+				for _, a := range t.Attr {
+					if a.Name.Local == "t" {
+						cellType = a.Value
+					}
+				}
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				v := value.String()
+				if cellType == "s" {
+					if i, err := strconv.Atoi(v); err == nil && i >= 0 && i < len(shared) {
+						v = shared[i]
+					}
+				}
+				row = append(row, strings.Join(strings.Fields(v), " "))
+			case "row":
+				for len(row) > 0 && row[len(row)-1] == "" {
+					row = row[:len(row)-1]
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package obj
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// rtfSkip are destinations holding no document text.
+	rtfSkip = map[string]bool{
+		"fonttbl": true, "colortbl": true, "stylesheet": true, "listtable": true,
+		"listoverridetable": true, "revtbl": true, "rsidtbl": true, "generator": true,
+		"pict": true, "object": true, "nonshppict": true, "fldinst": true,
+		"header": true, "headerl": true, "headerr": true, "headerf": true,
+		"footer": true, "footerl": true, "footerr": true, "footerf": true,
+		"themedata": true, "colorschememapping": true, "latentstyles": true,
+		"datastore": true, "xmlnstbl": true, "filetbl": true, "private": true,
+		"pgdsctbl": true, "bkmkstart": true, "bkmkend": true, "mmathPr": true,
+	}
+	rtfSymbols = map[string]string{
+		"par": "\n", "line": "\n", "sect": "\n", "page": "\n", "row": "\n",
+		"tab": "\t", "cell": "\t", "emdash": "—", "endash": "–", "bullet": "•",
+		"lquote": "‘", "rquote": "’", "ldblquote": "“", "rdblquote": "”",
+	}
+	// cp1252 maps the bytes of Windows-1252, the usual \ansicpg, that differ
+	// from Latin-1.
+	cp1252 = map[byte]rune{
+		0x80: '€', 0x85: '…', 0x91: '‘', 0x92: '’', 0x93: '“', 0x94: '”',
+		0x95: '•', 0x96: '–', 0x97: '—', 0x99: '™',
+	}
+)
+
+// rtfState is the part of the reader's state scoped to a group.
+type rtfState struct {
+	dest string // "" for body text
+	skip bool
+	uc   int // fallback characters following a \u
+}
+
+// extractRTF returns the text of an RTF document and the title, author and
+// creation time from its \info group.
+func extractRTF(b []byte, _ string) (Extracted, error) {
+	if !bytes.HasPrefix(bytes.TrimSpace(b), []byte(`{\rtf`)) {
+		return Extracted{}, ErrFileType
+	}
+	var (
+		out      = map[string]*strings.Builder{"": {}, "title": {}, "author": {}}
+		st       = rtfState{uc: 1}
+		stack    []rtfState
+		created  = make(map[string]int)
+		fallback int // characters left to drop after a \u
+	)
+	emit := func(s string) {
+		if w, ok := out[st.dest]; ok && !st.skip {
+			w.WriteString(s)
+		}
+	}
+	char := func(r rune) {
+		if fallback > 0 {
+			fallback--
+			return
+		}
+		emit(string(r))
+	}
+	for i := 0; i < len(b); i++ {
+		switch c := b[i]; c {
+		case '{':
+			stack = append(stack, st)
+		case '}':
+			if len(stack) > 0 {
+				st, stack = stack[len(stack)-1], stack[:len(stack)-1]
+			}
+		case '\r', '\n':
+		case '\\':
+			if i+1 >= len(b) {
+				break
+			}
+			i++
+			switch c = b[i]; {
+			case isASCIILetter(c):
+				j := i
+				for j < len(b) && isASCIILetter(b[j]) {
+					j++
+				}
+				k := j
+				if k < len(b) && (b[k] == '-' || isASCIIDigit(b[k])) {
+					k++
+					for k < len(b) && isASCIIDigit(b[k]) {
+						k++
+					}
+				}
+				word, param := string(b[i:j]), 0
+				if k > j {
+					param, _ = strconv.Atoi(string(b[j:k]))
+				}
+				if k < len(b) && b[k] == ' ' {
+					k++
+				}
+				i = k - 1
+
+				switch {
+				case rtfSymbols[word] != "":
+					emit(rtfSymbols[word])
+				case word == "u":
+					if param < 0 {
+						param += 65536
+					}
+					emit(string(rune(param)))
+					fallback = st.uc
+				case word == "uc":
+					st.uc = param
+				case word == "info":
+					st.dest = word
+				case st.dest == "info" && (word == "title" || word == "author" || word == "creatim"):
+					st.dest = word
+				case st.dest == "creatim":
+					created[word] = param
+				case rtfSkip[word]:
+					st.skip = true
+				}
+			case c == '\'':
+				if i+2 < len(b) {
+					if v, err := strconv.ParseUint(string(b[i+1:i+3]), 16, 8); err == nil {
+						char(cp1252Rune(byte(v)))
+					}
+					i += 2
+				}
+			case c == '*':
+				st.skip = true
+			case c == '~':
+				char(' ')
+			case c == '_':
+				char('-')
+			case c == '-':
+				// optional hyphen
+			case c == '\r' || c == '\n':
+				emit("\n")
+			default:
+				char(rune(c))
+			}
+		default:
+			char(cp1252Rune(c))
+		}
+	}
+
+	x := Extracted{Text: out[""].String(), Title: out["title"].String(), Author: out["author"].String()}
+	if created["yr"] > 0 {
+		x.Created = time.Date(created["yr"], time.Month(created["mo"]), created["dy"], created["hr"], created["min"], 0, 0, time.UTC)
+	}
+	return x, nil
+}
+
+func cp1252Rune(b byte) rune {
+	if r, ok := cp1252[b]; ok {
+		return r
+	}
+	return rune(b)
+}
+
+func isASCIILetter(c byte) bool { return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' }
+
+func isASCIIDigit(c byte) bool { return c >= '0' && c <= '9' }
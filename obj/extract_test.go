@@ -0,0 +1,149 @@
+package obj
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCore = `<?xml version="1.0" encoding="UTF-8"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:dcterms="http://purl.org/dc/terms/">
+<dc:title>Q3 Results</dc:title><dc:creator>Jane Analyst</dc:creator><dcterms:created>2021-10-28T09:00:00Z</dcterms:created>
+</cp:coreProperties>`
+
+func zipFiles(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestExtractHTML(t *testing.T) {
+	page := `<html><head><title>ACME | News</title>
+<meta property="og:title" content="ACME beats estimates">
+<meta name="author" content="J. Smith">
+<meta property="article:published_time" content="2022-03-01T14:00:00Z">
+<script>var x = 1;</script></head>
+<body><nav>Home Markets</nav><div class="cookie-banner">We use cookies</div>
+<article><h1>ACME beats estimates</h1><p>Revenue rose ` + strings.Repeat("sharply ", 30) + `</p>
+<div class="share-links">Tweet this</div><p>Margins held.</p></article>
+<footer>Copyright</footer></body></html>`
+	doc := &Document{ContentType: "text/html; charset=utf-8"}
+	x, err := extract(doc, []byte(page))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Title != "ACME beats estimates" || x.Author != "J. Smith" || !x.Created.Equal(time.Date(2022, 3, 1, 14, 0, 0, 0, time.UTC)) {
+		t.Fatalf("meta = %q, %q, %v", x.Title, x.Author, x.Created)
+	}
+	for _, s := range []string{"Home", "cookies", "Tweet", "Copyright", "var x"} {
+		if strings.Contains(x.Text, s) {
+			t.Errorf("boilerplate %q in %q", s, x.Text)
+		}
+	}
+	if !strings.HasPrefix(x.Text, "ACME beats estimates\nRevenue rose sharply") || !strings.HasSuffix(x.Text, "Margins held.") {
+		t.Fatalf("text = %q", x.Text)
+	}
+}
+
+func TestExtractXlsx(t *testing.T) {
+	b := zipFiles(t, map[string]string{
+		"docProps/core.xml":    testCore,
+		"xl/sharedStrings.xml": `<sst><si><t>Revenue</t></si><si><r><t>Net </t></r><r><t>income</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1"><v>1200</v></c></row>
+<row r="2"><c r="A2" t="s"><v>1</v></c><c r="B2"><f>B1/10</f><v>120</v></c><c r="C2" t="inlineStr"><is><t>est.</t></is></c></row>
+</sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData><row r="1"><c t="str"><v>Notes</v></c></row></sheetData></worksheet>`,
+	})
+	doc := &Document{ContentType: "application/octet-stream", Source: "https://acme.com/q3.xlsx?dl=1"}
+	x, err := extract(doc, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Revenue\t1200\nNet income\t120\test.\n\nNotes"; x.Text != want {
+		t.Fatalf("text = %q, want %q", x.Text, want)
+	}
+	if x.Title != "Q3 Results" || x.Author != "Jane Analyst" || x.Created.Year() != 2021 {
+		t.Fatalf("meta = %+v", x)
+	}
+	// the notes sheet has no figures
+	if len(x.Tables) != 1 || len(x.Tables[0].Rows) != 2 || x.Tables[0].Rows[0][2] != "" {
+		t.Fatalf("tables = %+v", x.Tables)
+	}
+}
+
+func TestExtractDocx(t *testing.T) {
+	b := zipFiles(t, map[string]string{
+		"[Content_Types].xml": `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">
+<Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/>
+<Override PartName="/docProps/core.xml" ContentType="application/vnd.openxmlformats-package.core-properties+xml"/></Types>`,
+		"docProps/core.xml": testCore,
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Strong quarter.</w:t></w:r></w:p></w:body></w:document>`,
+	})
+	x, err := extract(&Document{ContentType: mimeDocx}, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Text != "Strong quarter." || x.Title != "Q3 Results" {
+		t.Fatalf("extracted = %+v", x)
+	}
+}
+
+func TestExtractRTF(t *testing.T) {
+	rtf := `{\rtf1\ansi\ansicpg1252{\fonttbl{\f0 Arial;}}{\colortbl;\red0\green0\blue0;}` +
+		`{\info{\title Outlook 2023}{\author Sam Lee}{\creatim\yr2023\mo1\dy5\hr9\min30}}` +
+		`{\*\generator Riched20;}\f0 Caf\'e9 sales \u8364?5m\par Next\tab line \{ok\}}`
+	x, err := extract(&Document{ContentType: "application/rtf"}, []byte(rtf))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Café sales €5m\nNext\tline {ok}"; x.Text != want {
+		t.Fatalf("text = %q, want %q", x.Text, want)
+	}
+	if x.Title != "Outlook 2023" || x.Author != "Sam Lee" || !x.Created.Equal(time.Date(2023, 1, 5, 9, 30, 0, 0, time.UTC)) {
+		t.Fatalf("meta = %+v", x)
+	}
+}
+
+func TestExtractPlain(t *testing.T) {
+	x, err := extract(&Document{ContentType: "text/plain; charset=iso-8859-1"}, []byte("na\xefve  \r\n\r\n\r\n\r\nend\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x.Text != "naïve\n\nend" {
+		t.Fatalf("text = %q", x.Text)
+	}
+	if _, err = extract(&Document{ContentType: "application/zip"}, nil); err != ErrUnsupported {
+		t.Fatalf("zip err = %v", err)
+	}
+}
+
+func TestExtractXML(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="ISO-8859-1"?>
+<xbrl xmlns="http://www.xbrl.org/2003/instance"><context id="c"><period><instant>2023-09-30</instant></period></context>
+  <TradingSymbol contextRef="c">ACME</TradingSymbol>
+  <Description contextRef="c">Caf` + "\xe9" + ` &amp; bakery</Description>
+</xbrl>`
+	for _, c := range []*Document{{ContentType: "application/xbrl+xml"}, {ContentType: "text/xml"}, {Source: "https://example.com/acme-20230930.xml"}} {
+		x, err := extract(c, []byte(doc))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "2023-09-30\nACME\nCafé & bakery"; x.Text != want {
+			t.Fatalf("%+v: text = %q, want %q", c, x.Text, want)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package obj
+
+import "time"
+
+// Fact kinds.
+const (
+	FactPriceTarget = "price_target"
+	FactRating      = "rating"
+	FactEPS         = "eps_estimate"
+	FactRevenue     = "revenue_estimate"
+)
+
+// Ratings are normalized to one of these; Fact.Label keeps the wording the
+// firm used.
+const (
+	RatingBuy  = "buy"
+	RatingHold = "hold"
+	RatingSell = "sell"
+)
+
+// Fact is a figure or opinion stated in a document about one symbol, such as
+// a price target or an EPS estimate for a fiscal period.
+type Fact struct {
+	Kind     string
+	Symbol   string
+	Value    float64 `msgpack:",omitempty"` // price targets and estimates, in units of Currency
+	Currency string  `msgpack:",omitempty"`
+	Period   string  `msgpack:",omitempty"` // fiscal period of an estimate, e.g. "FY2024" or "Q3 2024"
+	Rating   string  `msgpack:",omitempty"`
+	Label    string  `msgpack:",omitempty"` // the rating as written, e.g. "Overweight"
+	Firm     string  `msgpack:",omitempty"`
+	Analyst  string  `msgpack:",omitempty"`
+	Document string  // id of the document stating it
+	Date     time.Time
+	Context  string `msgpack:",omitempty"` // the sentence it was found in
+}
@@ -12,3 +12,12 @@ type Imitator interface {
 	Get(*http.Request) (*http.Response, error)
 	Post(*http.Request) (*http.Response, error)
 }
+
+// Stage processes a document once it has been saved and its text extracted.
+type Stage interface {
+	Process(doc *Document, text string) error
+}
+
+type StageFunc func(doc *Document, text string) error
+
+func (f StageFunc) Process(doc *Document, text string) error { return f(doc, text) }
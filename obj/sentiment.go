@@ -0,0 +1,13 @@
+package obj
+
+// Sentiment is the tone of a document's text, measured by counting words from
+// finance specific word lists. The list scores are the share of the scored
+// words found in each list.
+type Sentiment struct {
+	Words       int
+	Positive    float64
+	Negative    float64
+	Uncertainty float64
+	Litigious   float64
+	Tone        float64 // (positive - negative) / (positive + negative), from -1 to 1
+}
@@ -0,0 +1,276 @@
+package obj
+
+import (
+	"encoding/csv"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	minTableRows    = 3 // rows of a table found in laid out text, header included
+	minTableColumns = 2
+	maxColSpan      = 50
+)
+
+var (
+	// rexNumericCell matches figures as they appear in financial tables:
+	// "1,234", "(56.7)", "-3%", "$4.2bn", "12.5x", "2023E".
+	rexNumericCell = regexp.MustCompile(`^[-–+(]?[$€£¥]?\d[\d,]*(\.\d+)?(%|x|bn|mn|m|k|[AEF])?\)?$`)
+	rexYearCell    = regexp.MustCompile(`^(FY|CY|Q[1-4])?\s?'?((19|20)\d{2}|\d{2})[AEFP]?$`)
+	// rexColumnGap separates the cells of a line of laid out text.
+	rexColumnGap = regexp.MustCompile(`\S+(?: \S+)*`)
+)
+
+// Table is a table found in a document. Rows all have the same number of
+// cells; Header is set when the first row holds column names.
+type Table struct {
+	Page    int    `json:",omitempty"` // for PDFs, numbered from 1
+	Caption string `json:",omitempty"`
+	Header  bool
+	Rows    [][]string
+}
+
+// WriteCSV writes the rows of t to w as CSV.
+func (t Table) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(t.Rows); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+func isNumericCell(s string) bool {
+	return rexNumericCell.MatchString(strings.ReplaceAll(s, " ", ""))
+}
+
+// looksTabular reports whether rows have the shape of a data table rather
+// than page layout: enough rows and columns, and some figures in them.
+func looksTabular(rows [][]string) bool {
+	if len(rows) < 2 || len(rows[0]) < minTableColumns {
+		return false
+	}
+	for _, r := range rows {
+		for _, c := range r[1:] {
+			if isNumericCell(c) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// padRows makes every row as long as the longest one.
+func padRows(rows [][]string) [][]string {
+	width := 0
+	for _, r := range rows {
+		if len(r) > width {
+			width = len(r)
+		}
+	}
+	for i, r := range rows {
+		for len(r) < width {
+			r = append(r, "")
+		}
+		rows[i] = r
+	}
+	return rows
+}
+
+// htmlTable returns the table at n if it holds data. Tables used for layout,
+// recognised by other tables nested in them or by having no figures, are
+// skipped.
+func htmlTable(n *html.Node) (Table, bool) {
+	var (
+		t      Table
+		heads  []bool // whether each row is all <th> or in <thead>
+		nested bool
+		walk   func(c *html.Node, inHead bool)
+	)
+	walk = func(c *html.Node, inHead bool) {
+		for ; c != nil; c = c.NextSibling {
+			if c.Type != html.ElementNode {
+				continue
+			}
+			switch c.DataAtom {
+			case atom.Table:
+				nested = true
+			case atom.Caption:
+				t.Caption = nodeText(c)
+			case atom.Thead:
+				walk(c.FirstChild, true)
+			case atom.Tbody, atom.Tfoot:
+				walk(c.FirstChild, false)
+			case atom.Tr:
+				row, allTh := make([]string, 0), true
+				for cell := c.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.Type != html.ElementNode || cell.DataAtom != atom.Td && cell.DataAtom != atom.Th {
+						continue
+					}
+					allTh = allTh && cell.DataAtom == atom.Th
+					row = append(row, nodeText(cell))
+					span, _ := strconv.Atoi(attrOf(cell, "colspan"))
+					for i := 1; i < span && i < maxColSpan; i++ {
+						row = append(row, "")
+					}
+				}
+				if len(row) > 0 {
+					t.Rows = append(t.Rows, row)
+					heads = append(heads, inHead || allTh)
+				}
+			}
+		}
+	}
+	walk(n.FirstChild, false)
+	if nested {
+		return t, false
+	}
+	t.Rows = padRows(t.Rows)
+	t.Header = len(heads) > 0 && heads[0]
+	return t, looksTabular(t.Rows)
+}
+
+// layoutCell is a cell of a line of laid out text and where it starts and
+// ends on the line.
+type layoutCell struct {
+	text       string
+	start, end int
+}
+
+// layoutTables finds the tables in text laid out the way it appears on the
+// page, as pdftotext -layout writes it, with pages separated by form feeds.
+// A table is a run of lines split into cells by wide gaps, most of them with
+// figures in them.
+func layoutTables(text string) []Table {
+	tables := make([]Table, 0)
+	for p, page := range strings.Split(text, "\f") {
+		var block [][]layoutCell
+		flush := func() {
+			if t, ok := layoutTable(block); ok {
+				t.Page = p + 1
+				tables = append(tables, t)
+			}
+			block = nil
+		}
+		blank := 0
+		for _, line := range strings.Split(page, "\n") {
+			cells := splitLayoutLine(line)
+			switch {
+			case len(cells) >= minTableColumns:
+				blank = 0
+				block = append(block, cells)
+			case len(cells) == 0 && blank == 0 && len(block) > 0:
+				// tables often have a blank line between sections
+				blank++
+			default:
+				flush()
+				blank = 0
+			}
+		}
+		flush()
+	}
+	return tables
+}
+
+// splitLayoutLine splits a line where two or more spaces separate text.
+func splitLayoutLine(line string) []layoutCell {
+	line = strings.ReplaceAll(line, "\t", "    ")
+	cells := make([]layoutCell, 0)
+	for _, loc := range rexColumnGap.FindAllStringIndex(line, -1) {
+		cells = append(cells, layoutCell{text: line[loc[0]:loc[1]], start: loc[0], end: loc[1]})
+	}
+	return cells
+}
+
+func layoutTable(block [][]layoutCell) (Table, bool) {
+	if len(block) < minTableRows {
+		return Table{}, false
+	}
+	// figures are right aligned, so columns are found by where the cells of
+	// the widest rows end
+	width := 0
+	for _, r := range block {
+		if len(r) > width {
+			width = len(r)
+		}
+	}
+	ends, counts := make([]int, width), make([]int, width)
+	left := -1
+	for _, r := range block {
+		if left < 0 || r[0].start < left {
+			left = r[0].start
+		}
+		if len(r) == width {
+			for i, c := range r {
+				ends[i] += c.end
+				counts[i]++
+			}
+		}
+	}
+	for i := range ends {
+		ends[i] /= counts[i]
+	}
+
+	var t Table
+	numeric := 0
+	for _, r := range block {
+		row := make([]string, width)
+		hasFigure, col := false, -1
+		for i, c := range r {
+			switch {
+			case len(r) == width:
+				col = i
+			case i == 0 && c.start <= left+1:
+				// row labels are left aligned
+				col = 0
+			default:
+				// leave room for the cells still to place
+				col = nearestColumn(ends, c.end, col+1, width-len(r)+i)
+			}
+			row[col] = c.text
+			hasFigure = hasFigure || i > 0 && isNumericCell(c.text)
+		}
+		if hasFigure {
+			numeric++
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	if numeric*2 < len(block) {
+		return Table{}, false
+	}
+	t.Header = isHeaderRow(t.Rows[0])
+	return t, true
+}
+
+// nearestColumn returns the column from lo to hi ending closest to end.
+func nearestColumn(ends []int, end, lo, hi int) int {
+	best := lo
+	for i := lo; i <= hi; i++ {
+		if abs(ends[i]-end) < abs(ends[best]-end) {
+			best = i
+		}
+	}
+	return best
+}
+
+// isHeaderRow reports whether row names columns: no figures, though years
+// and periods such as "2023E" or "Q3 22" are allowed.
+func isHeaderRow(row []string) bool {
+	for _, c := range row {
+		if isNumericCell(c) && !rexYearCell.MatchString(c) {
+			return false
+		}
+	}
+	return true
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
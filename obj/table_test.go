@@ -0,0 +1,84 @@
+package obj
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestLayoutTables(t *testing.T) {
+	layout := `ACME Corp                                         Equity Research
+
+We expect revenue growth to continue through the
+forecast period, driven by new contracts.
+
+Income statement ($m)        2022A       2023E       2024E
+Revenue                      1,204       1,390       1,512
+Gross profit                   512         601
+Operating margin (%)          18.2        19.5        20.1
+EPS ($)                       (0.42)       0.15        0.38
+
+Source: company reports` + "\f" + `Page two has no tables
+Analyst   Jane Smith`
+
+	tables := layoutTables(layout)
+	if len(tables) != 1 {
+		t.Fatalf("tables = %+v", tables)
+	}
+	want := [][]string{
+		{"Income statement ($m)", "2022A", "2023E", "2024E"},
+		{"Revenue", "1,204", "1,390", "1,512"},
+		{"Gross profit", "512", "601", ""},
+		{"Operating margin (%)", "18.2", "19.5", "20.1"},
+		{"EPS ($)", "(0.42)", "0.15", "0.38"},
+	}
+	if tb := tables[0]; !tb.Header || tb.Page != 1 || !reflect.DeepEqual(tb.Rows, want) {
+		t.Fatalf("table = %+v", tb)
+	}
+
+	var buf bytes.Buffer
+	if err := tables[0].WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if line, _ := buf.ReadString('\n'); line != "Income statement ($m),2022A,2023E,2024E\n" {
+		t.Fatalf("csv header = %q", line)
+	}
+}
+
+func TestHTMLTables(t *testing.T) {
+	page := `<html><body>
+<table><tr><td><table><tr><td>Menu</td><td>1</td></tr></table></td></tr></table>
+<table><tr><td>Contact</td><td>Email us</td></tr><tr><td>Phone</td><td>Call us</td></tr></table>
+<table><caption>Estimates</caption>
+<thead><tr><th></th><th>FY23</th><th>FY24</th></tr></thead>
+<tbody><tr><td>Revenue</td><td>10.2</td><td>11.0</td></tr>
+<tr><td colspan="2">Net income</td><td>1.1</td></tr></tbody></table>
+</body></html>`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "text/html")
+		w.Write([]byte(page))
+	}))
+	defer srv.Close()
+
+	Setup(Config{FileStorePath: t.TempDir()})
+	doc := &Document{Item: NewItem(TDocument), Source: srv.URL + "/estimates"}
+	doc.Id = GetSignature([]byte(doc.Source))
+	if err := docMgr.save(context.Background(), doc); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := doc.LoadTables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Table{Caption: "Estimates", Header: true, Rows: [][]string{
+		{"", "FY23", "FY24"},
+		{"Revenue", "10.2", "11.0"},
+		{"Net income", "", "1.1"},
+	}}
+	if doc.Tables != 1 || len(tables) != 1 || !reflect.DeepEqual(tables[0], want) {
+		t.Fatalf("tables = %d, %+v", doc.Tables, tables)
+	}
+}
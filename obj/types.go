@@ -3,3 +3,15 @@ package obj
 const (
 	TDocument = "doc"
 )
+
+// Document types, see Document.Type.
+const (
+	Type10K          = "10-K"
+	Type10Q          = "10-Q"
+	Type8K           = "8-K"
+	TypeTranscript   = "earnings call transcript"
+	TypeResearch     = "research note"
+	TypePresentation = "investor presentation"
+	TypeNews         = "news article"
+	TypeBlog         = "blog"
+)
@@ -0,0 +1,153 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mrod502/stockscraper/obj"
+	"golang.org/x/net/html"
+)
+
+func NewBingClient(cfg Config) *BingClient {
+	return &BingClient{p: &BParser{}, cfg: cfg, b: newBackoff()}
+}
+
+type BingClient struct {
+	p   *BParser
+	cfg Config
+	b   *backoff
+}
+
+func (c *BingClient) Scrape(ctx context.Context, req Request) (d []*obj.Document, err error) {
+	if err = c.b.check(EngineBing); err != nil {
+		return nil, err
+	}
+	q, err := c.cfg.Query(req)
+	if err != nil {
+		return nil, err
+	}
+	d, err = c.cfg.paginate(ctx, req, func(page int) ([]*obj.Document, error) {
+		return searchPage(ctx, EngineBing, buildBingUri(q, page), c.p)
+	})
+	c.b.observe(err)
+	for _, doc := range d {
+		doc.Symbols = []string{req.Symbol}
+	}
+	return
+}
+
+// BParser parses Bing result pages. Each organic result is an
+// <li class="b_algo"> holding an <h2><a href> title link and a caption. The
+// link is usually a /ck/a?u= click-tracking redirect.
+type BParser struct {
+}
+
+func (p *BParser) Parse(b []byte) (d []*obj.Document, err error) {
+	d = make([]*obj.Document, 0, 10)
+	z := html.NewTokenizer(bytes.NewReader(b))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return d, nil
+			}
+			return d, z.Err()
+		case html.StartTagToken:
+			if t := z.Token(); t.Data == "li" && hasClass(t.Attr, "b_algo") {
+				if doc := p.parseSearchResult(z); doc.Source != "" {
+					d = append(d, doc)
+				}
+			}
+		}
+	}
+}
+
+func (p *BParser) parseSearchResult(z *html.Tokenizer) *obj.Document {
+	var doc = new(obj.Document)
+	doc.Item = obj.NewItem(obj.TDocument)
+
+	var inTitle bool
+	var caption strings.Builder
+	for level := 1; level > 0; {
+		switch z.Next() {
+		case html.ErrorToken:
+			return doc
+		case html.StartTagToken:
+			t := z.Token()
+			if !voidElements[t.Data] {
+				level++
+			}
+			switch {
+			case t.Data == "h2":
+				inTitle = true
+			case t.Data == "a" && inTitle && doc.Source == "":
+				if href, ok := getHref(t.Attr); ok {
+					if v, err := stripQuery(unwrapBing(href)); err == nil {
+						doc.Source = v
+						doc.ContentType = getLinkContentType(v)
+					}
+				}
+			}
+		case html.EndTagToken:
+			level--
+			if name, _ := z.TagName(); string(name) == "h2" {
+				inTitle = false
+			}
+		case html.TextToken:
+			if inTitle {
+				doc.Title += string(z.Text())
+			} else {
+				caption.Write(z.Text())
+			}
+		}
+	}
+	doc.Title = strings.TrimSpace(doc.Title)
+	if t, err := parseGoogleDate(caption.String()); err == nil {
+		doc.PostedDate = t
+	} else if y := gYearRex.FindString(caption.String()); y != "" {
+		doc.PostedDate, _ = time.Parse("2006", y)
+	}
+	return doc
+}
+
+// unwrapBing returns the target of a bing.com/ck/a redirect link, whose u
+// parameter is "a1" followed by the base64url encoded target, or href itself.
+func unwrapBing(href string) string {
+	u, err := url.Parse(href)
+	if err != nil || !strings.HasSuffix(u.Hostname(), "bing.com") || u.Path != "/ck/a" {
+		return href
+	}
+	enc := strings.TrimPrefix(u.Query().Get("u"), "a1")
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(enc, "="))
+	if err != nil {
+		return href
+	}
+	if target, err := url.Parse(string(b)); err == nil && (target.Scheme == "http" || target.Scheme == "https") {
+		return target.String()
+	}
+	return href
+}
+
+// voidElements never have an end tag, so they don't change the nesting level.
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+func hasClass(attrs []html.Attribute, class string) bool {
+	for _, a := range attrs {
+		if a.Key == "class" {
+			for _, c := range strings.Fields(a.Val) {
+				if c == class {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
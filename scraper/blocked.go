@@ -0,0 +1,151 @@
+package scraper
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	minBackoff = 30 * time.Second
+	maxBackoff = time.Hour
+)
+
+var (
+	ErrBlocked = errors.New("blocked by search engine")
+)
+
+// blockMarkers are lowercase fragments of the CAPTCHA, consent and rate limit
+// interstitials served instead of results.
+var blockMarkers = []string{
+	"our systems have detected unusual traffic",
+	"unusual traffic from your computer network",
+	"/sorry/index",
+	"g-recaptcha",
+	"before you continue to google",
+	"consent.google.com",
+	"anomaly-modal",
+	"bots use duckduckgo too",
+	"b_captcha",
+	"verify you are a human",
+	"request rate threshold exceeded",
+}
+
+// BlockedError reports that an engine served a block page. RetryAfter is the
+// time to wait before the engine should be queried again.
+type BlockedError struct {
+	Engine     string
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("%s: %s (%s), retry after %s", ErrBlocked, e.Engine, e.Reason, e.RetryAfter.Round(time.Second))
+}
+
+func (e *BlockedError) Is(target error) bool { return target == ErrBlocked }
+
+// detectBlock returns a BlockedError if res and its body b are a block page
+// rather than results.
+func detectBlock(engine string, res *http.Response, b []byte) error {
+	var reason string
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests:
+		reason = res.Status
+	case res.StatusCode == http.StatusServiceUnavailable, res.StatusCode == http.StatusForbidden:
+		reason = res.Status
+	case res.Request != nil && (strings.Contains(res.Request.URL.Path, "/sorry/") || strings.HasPrefix(res.Request.URL.Host, "consent.")):
+		reason = "redirected to " + res.Request.URL.Host + res.Request.URL.Path
+	default:
+		lower := bytes.ToLower(b)
+		for _, m := range blockMarkers {
+			if bytes.Contains(lower, []byte(m)) {
+				reason = m
+				break
+			}
+		}
+	}
+	if reason == "" {
+		return nil
+	}
+	return &BlockedError{Engine: engine, Reason: reason, RetryAfter: retryAfter(res.Header.Get("retry-after"))}
+}
+
+// retryAfter parses a Retry-After header given in seconds or as a date.
+func retryAfter(h string) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(h); err == nil {
+		return time.Duration(s) * time.Second
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// backoff keeps an engine from being queried while it is blocking us. Each
+// consecutive block doubles the wait, starting at minBackoff.
+type backoff struct {
+	l        *sync.Mutex
+	until    time.Time
+	failures int
+}
+
+func newBackoff() *backoff { return &backoff{l: &sync.Mutex{}} }
+
+// check returns a BlockedError while the engine is backing off.
+func (b *backoff) check(engine string) error {
+	b.l.Lock()
+	defer b.l.Unlock()
+	if wait := time.Until(b.until); wait > 0 {
+		return &BlockedError{Engine: engine, Reason: "backing off", RetryAfter: wait}
+	}
+	return nil
+}
+
+// observe records the outcome of a query. A BlockedError starts or extends
+// the backoff and has its RetryAfter set to the backoff period.
+func (b *backoff) observe(err error) {
+	b.l.Lock()
+	defer b.l.Unlock()
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) {
+		if err == nil {
+			b.failures = 0
+		}
+		return
+	}
+	wait := minBackoff << b.failures
+	if wait > maxBackoff || wait <= 0 {
+		wait = maxBackoff
+	}
+	if blocked.RetryAfter > wait {
+		wait = blocked.RetryAfter
+	}
+	b.failures++
+	b.until = time.Now().Add(wait)
+	blocked.RetryAfter = wait
+}
+
+// Blocked returns the block with the shortest wait if every failed engine was
+// blocked.
+func (e EngineErrors) Blocked() (*BlockedError, bool) {
+	var first *BlockedError
+	for _, err := range e {
+		var blocked *BlockedError
+		if !errors.As(err, &blocked) {
+			return nil, false
+		}
+		if first == nil || blocked.RetryAfter < first.RetryAfter {
+			first = blocked
+		}
+	}
+	return first, first != nil
+}
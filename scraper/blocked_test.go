@@ -0,0 +1,53 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDetectBlock(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/limited":
+			w.Header().Set("retry-after", "120")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case "/captcha":
+			w.Write([]byte(`<html><body>Our systems have detected unusual traffic from your computer network.</body></html>`))
+		default:
+			w.Write([]byte(`<html><body><div class="g"></div></body></html>`))
+		}
+	}))
+	defer srv.Close()
+
+	_, err := searchPage(context.Background(), EngineGoogle, srv.URL+"/limited", &GParser{})
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || blocked.RetryAfter != 2*time.Minute {
+		t.Fatalf("expected block with retry after, got %v", err)
+	}
+	if _, err = searchPage(context.Background(), EngineGoogle, srv.URL+"/captcha", &GParser{}); !errors.Is(err, ErrBlocked) {
+		t.Fatalf("expected ErrBlocked, got %v", err)
+	}
+	if _, err = searchPage(context.Background(), EngineGoogle, srv.URL+"/results", &GParser{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	b := newBackoff()
+	if err := b.check(EngineBing); err != nil {
+		t.Fatal(err)
+	}
+	b.observe(&BlockedError{Engine: EngineBing, Reason: "captcha"})
+	err := b.check(EngineBing)
+	var blocked *BlockedError
+	if !errors.As(err, &blocked) || blocked.RetryAfter <= 0 || blocked.RetryAfter > minBackoff {
+		t.Fatalf("expected engine to back off, got %v", err)
+	}
+	if _, ok := (EngineErrors{EngineBing: err}).Blocked(); !ok {
+		t.Fatal("expected EngineErrors to report the block")
+	}
+}
@@ -1,34 +1,154 @@
 package scraper
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 
 	"github.com/mrod502/stockscraper/obj"
 )
 
 const (
-	googleSearch = "https://www.google.com/search"
+	googleSearch     = "https://www.google.com/search"
+	bingSearch       = "https://www.bing.com/search"
+	duckDuckGoSearch = "https://html.duckduckgo.com/html/"
+)
+
+// Engine names accepted in Config.Engines.
+const (
+	EngineGoogle     = "google"
+	EngineBing       = "bing"
+	EngineDuckDuckGo = "duckduckgo"
+	EngineEdgar      = "edgar"
+)
+
+const DefaultTemplate = "research"
+
+var (
+	ErrUnknownEngine   = errors.New("unknown search engine")
+	ErrUnknownTemplate = errors.New("unknown query template")
+	DefaultEngines     = []string{EngineGoogle}
+
+	// DefaultTemplates are the query templates available when Config.Templates
+	// does not override them. See Config.Query for the placeholders.
+	DefaultTemplates = map[string]string{
+		DefaultTemplate:         "{symbol} equity research filetype:{filetype}",
+		"earnings transcript":   "{company} {symbol} earnings call transcript {year} filetype:{filetype}",
+		"investor presentation": "{company} {symbol} investor presentation {year} filetype:{filetype}",
+		"annual report":         "{company} {symbol} annual report {year} filetype:{filetype}",
+	}
 )
 
 type Config struct {
+	Engines     []string          `yaml:"engines"`       // enabled engines in order of preference, defaults to DefaultEngines
+	Templates   map[string]string `yaml:"templates"`     // named query templates, added to DefaultTemplates
+	MaxPages    int               `yaml:"max_pages"`     // upper bound for Request.Pages, defaults to DefaultMaxPages
+	PageDelayMs int               `yaml:"page_delay_ms"` // wait between result pages, defaults to DefaultPageDelay
+	Edgar       EdgarConfig       `yaml:"edgar"`
+}
+
+// Request describes what to scrape. Template names the query template used
+// by search engines and defaults to DefaultTemplate. Pages is the number of
+// result pages to read (default 1) and MaxResults caps the number of
+// documents returned (0 for no limit).
+type Request struct {
+	Symbol     string
+	FileType   string
+	Company    string
+	Year       int
+	Template   string
+	Pages      int
+	MaxResults int
+}
+
+// Query renders the search query for r. The placeholders {symbol},
+// {company}, {year} and {filetype} are replaced by the fields of r; empty
+// fields are dropped from the query.
+func (cfg Config) Query(r Request) (string, error) {
+	name := r.Template
+	if name == "" {
+		name = DefaultTemplate
+	}
+	tpl, ok := cfg.Templates[name]
+	if !ok {
+		if tpl, ok = DefaultTemplates[name]; !ok {
+			return "", fmt.Errorf("%w: %s", ErrUnknownTemplate, name)
+		}
+	}
+	year := ""
+	if r.Year > 0 {
+		year = strconv.Itoa(r.Year)
+	}
+	q := strings.NewReplacer(
+		"{symbol}", r.Symbol,
+		"{company}", r.Company,
+		"{year}", year,
+		"{filetype}", r.FileType,
+	).Replace(tpl)
+	if r.FileType == "" {
+		q = strings.ReplaceAll(q, "filetype:", "")
+	}
+	return strings.Join(strings.Fields(q), " "), nil
+}
+
+// NewClient returns the client for the named engine.
+func NewClient(engine string, cfg Config) (Client, error) {
+	switch strings.ToLower(engine) {
+	case EngineGoogle:
+		return NewGoogleClient(cfg), nil
+	case EngineBing:
+		return NewBingClient(cfg), nil
+	case EngineDuckDuckGo:
+		return NewDuckDuckGoClient(cfg), nil
+	case EngineEdgar:
+		return NewEdgarClient(cfg.Edgar), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEngine, engine)
+	}
+}
+
+// EnabledEngines returns cfg.Engines or DefaultEngines when none are set.
+func (cfg Config) EnabledEngines() []string {
+	if len(cfg.Engines) == 0 {
+		return DefaultEngines
+	}
+	return cfg.Engines
+}
+
+// The build*Uri functions take a zero based result page.
+
+func buildGoogleUri(q string, page int) (u string) {
+	q = url.QueryEscape(q)
+	u = fmt.Sprintf(googleSearch+"?q=%s&oq=%s&sourceid=chrome&ie=UTF-8", q, q)
+	if page > 0 {
+		u += fmt.Sprintf("&start=%d", page*10)
+	}
+	return
 }
 
-func buildGoogleUri(q string) (u string) {
-	q = strings.ReplaceAll(q, " ", "+")
-	q = strings.ReplaceAll(q, ":", `%3A`)
-	return fmt.Sprintf(googleSearch+"?q=%s&oq=%s&sourceid=chrome&ie=UTF-8", q, q)
+func buildBingUri(q string, page int) (u string) {
+	q = url.QueryEscape(q)
+	u = fmt.Sprintf(bingSearch+"?q=%s&form=QBRE&qs=n", q)
+	if page > 0 {
+		u += fmt.Sprintf("&first=%d", page*10+1)
+	}
+	return
 }
 
-func buildBingUri(q string) (u string) {
-	q = strings.ReplaceAll(q, " ", "+")
-	q = strings.ReplaceAll(q, ":", `%3A`)
-	return fmt.Sprintf(googleSearch+"?q=%s&oq=%s&sc=0-33&qs=n", q, q)
+func buildDuckDuckGoUri(q string, page int) (u string) {
+	u = duckDuckGoSearch + "?q=" + url.QueryEscape(q)
+	if page > 0 {
+		u += fmt.Sprintf("&s=%d&dc=%d", page*30, page*30+1)
+	}
+	return
 }
 
 type Client interface {
-	Scrape(symbol, filetype string) ([]*obj.Document, error)
+	Scrape(ctx context.Context, r Request) ([]*obj.Document, error)
 }
 
 func setGoogleHeaders(req *http.Request) {
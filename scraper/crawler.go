@@ -1,28 +1,46 @@
 package scraper
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"regexp"
+	"net/url"
 	"strings"
 	"sync"
+	"time"
 
 	gocache "github.com/mrod502/go-cache"
 	"github.com/mrod502/logger"
 	"go.uber.org/atomic"
 )
 
-var (
-	ptnFindHref = regexp.MustCompile(`href="([^"]+)"`)
-)
-
 type ResponseHandler func(*http.Response) error
 
+// LinkFilter decides whether a link found on a page is worth fetching. u is
+// the resolved, canonical URL.
+type LinkFilter func(u string, l Link) bool
+
 type CrawlerParams struct {
-	Limit uint64
-	Seeds []string
+	Limit     uint64
+	Seeds     []string
+	UserAgent string  `json:"-"` // sent with every request and matched against robots.txt, defaults to DefaultUserAgent; set by the server, not the caller
+	HostRate  float64 // requests per second to a single host, defaults to DefaultHostRate
+	HostBurst int     // defaults to DefaultHostBurst
+
+	MaxDepth   int      // links followed away from a seed, 0 for no limit
+	SameDomain bool     // only follow links on a seed's registrable domain, e.g. apple.com
+	SameHost   bool     // only follow links on a seed's host, e.g. investor.apple.com
+	AllowHosts []string // if set, only follow links to these hosts and their subdomains
+	DenyHosts  []string // never follow links to these hosts and their subdomains
+	Include    []string // if set, only follow URLs matching one of these regexes
+	Exclude    []string // never follow URLs matching one of these regexes
+	Ignore     []string // substrings of URLs to skip, defaults to DefaultIgnore
+
+	Job          string  // crawl job id the frontier is stored under
+	RecrawlAfter float64 // hours before a URL fetched by any crawl is fetched again, defaults to DefaultRecrawlAfter, negative to always fetch
 }
 
 type Crawler struct {
@@ -31,39 +49,70 @@ type Crawler struct {
 	seeds              *gocache.Cache[bool, string]
 	applicationHandler func(*http.Response) error
 	textHandler        func(*http.Response) error
+	linkFilter         LinkFilter
 	requestCount       *atomic.Uint64
+	documentCount      *atomic.Uint64
+	errorCount         *atomic.Uint64
 	maxRequests        uint64
 	wg                 *sync.WaitGroup
-	workers            []chan string
+	workersWg          *sync.WaitGroup
+	doneOnce           *sync.Once
+	inflight           *atomic.Int64 // links queued or being fetched
+	workers            []chan Link
 	currentWorker      *atomic.Uint32
 	ignore             *gocache.Cache[bool, string]
+	scope              *scope
+	frontier           *Frontier
+	stop               chan struct{}
+	stopOnce           *sync.Once
+	interrupted        *atomic.Bool
+	ctx                context.Context
+	cli                *http.Client
+	userAgent          string
+	robots             *robotsCache
+	limiter            *hostLimiter
 }
 
 func NewCrawler(params CrawlerParams) (c *Crawler, err error) {
 	if len(params.Seeds) == 0 {
 		return nil, errors.New("cannot initialize with no seeds")
 	}
+	if params.UserAgent == "" {
+		params.UserAgent = DefaultUserAgent
+	}
+	if params.Ignore == nil {
+		params.Ignore = DefaultIgnore
+	}
+	sc, err := newScope(params)
+	if err != nil {
+		return nil, err
+	}
+	cli := &http.Client{Timeout: 30 * time.Second}
 	c = &Crawler{
+		cli:           cli,
+		userAgent:     params.UserAgent,
+		robots:        newRobotsCache(params.UserAgent, cli),
+		limiter:       newHostLimiter(params.HostRate, params.HostBurst),
 		visited:       gocache.New[bool, string](),
 		seeds:         gocache.New[bool, string](),
 		requestCount:  atomic.NewUint64(0),
+		documentCount: atomic.NewUint64(0),
+		errorCount:    atomic.NewUint64(0),
 		maxRequests:   params.Limit,
 		wg:            &sync.WaitGroup{},
+		workersWg:     &sync.WaitGroup{},
+		doneOnce:      &sync.Once{},
+		inflight:      atomic.NewInt64(0),
 		currentWorker: atomic.NewUint32(0),
-		ignore: gocache.New(map[string]bool{
-			//set some ignored patterns so we don't get blacklisted :)
-			"googlesyndication": true,
-			"/video":            true,
-			"viewkey":           true,
-			"youtube.com":       true,
-			"adservices":        true,
-			"adsense":           true,
-			"google":            true,
-			".png":              true,
-			".ico":              true,
-			".js":               true,
-			"reddit.com":        true,
-		}),
+		ignore:        gocache.New[bool, string](),
+		scope:         sc,
+		stop:          make(chan struct{}),
+		stopOnce:      &sync.Once{},
+		interrupted:   atomic.NewBool(false),
+		ctx:           context.Background(),
+	}
+	for _, v := range params.Ignore {
+		c.ignore.Set(v, true)
 	}
 	for _, seed := range params.Seeds {
 		c.seeds.Set(seed, true)
@@ -79,24 +128,129 @@ func (c *Crawler) SetTextFileHandler(f ResponseHandler) {
 	c.textHandler = f
 }
 
-func (c *Crawler) Crawl(numWorkers uint8) error {
+// SetLinkFilter sets a filter run on every link before it is queued, so links
+// can be skipped on their URL, kind and anchor text without fetching them.
+func (c *Crawler) SetLinkFilter(f LinkFilter) {
+	c.linkFilter = f
+}
+
+// SetFrontier stores the crawl's queue and visited set in f, so that the
+// crawl can be paused and resumed by a later Crawler for the same job.
+func (c *Crawler) SetFrontier(f *Frontier) {
+	c.frontier = f
+}
+
+// CrawlStats is the progress of a crawl.
+type CrawlStats struct {
+	Requests  uint64 // pages fetched
+	Documents uint64 // pages passed to a file handler
+	Errors    uint64 // failed fetches and handler errors
+	Queued    int64  // links waiting to be fetched
+}
+
+func (c *Crawler) Stats() CrawlStats {
+	return CrawlStats{
+		Requests:  c.requestCount.Load(),
+		Documents: c.documentCount.Load(),
+		Errors:    c.errorCount.Load(),
+		Queued:    c.inflight.Load(),
+	}
+}
+
+// Crawl runs until the request limit is reached, there are no links left,
+// Pause is called or ctx is canceled. Canceling ctx drops the crawl's
+// frontier. Requests in flight are aborted and the workers have exited by the
+// time Crawl returns.
+func (c *Crawler) Crawl(ctx context.Context, numWorkers uint8) error {
 	if numWorkers < 1 {
 		numWorkers = 1
 	}
+	pending := make([]Link, 0, len(c.seeds.GetKeys()))
+	for _, seed := range c.seeds.GetKeys() {
+		if u, err := url.Parse(seed); err == nil {
+			pending = append(pending, Link{URL: canonicalURL(u), Kind: LinkSeed})
+		}
+	}
+	if c.frontier != nil {
+		var (
+			visited []string
+			st      CrawlStats
+			err     error
+		)
+		if pending, visited, st, err = c.frontier.start(pending); err != nil {
+			return err
+		}
+		for _, v := range visited {
+			c.visited.Set(v, true)
+		}
+		c.requestCount.Store(st.Requests)
+		c.documentCount.Store(st.Documents)
+		c.errorCount.Store(st.Errors)
+	}
+	if len(pending) == 0 || c.requestCount.Load() >= c.maxRequests {
+		return c.frontier.finish(CrawlDone)
+	}
+	reqCtx, abort := context.WithCancel(ctx)
+	defer abort()
+	c.ctx = reqCtx
+
 	c.wg.Add(1)
 	c.buildWorkers(numWorkers)
-	for _, seed := range c.seeds.GetKeys() {
-		c.dispatchWork(seed)
+	c.inflight.Inc() // keep the crawl from finishing while seeding
+	for _, l := range pending {
+		c.dispatchWork(l)
+	}
+	c.release()
+	done := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		c.Pause()
+		abort()
+		c.workersWg.Wait()
+		return c.frontier.finish(CrawlDone)
+	case <-ctx.Done():
+		c.Pause()
+		c.workersWg.Wait()
+		return c.frontier.finish(CrawlCanceled)
+	case <-c.stop:
+		// links being fetched stay queued and are fetched again on resume
+		abort()
+		c.workersWg.Wait()
+		if c.interrupted.Load() {
+			return c.frontier.save()
+		}
+		return c.frontier.pause()
 	}
-	c.wg.Wait()
+}
 
-	return nil
+// Pause stops the workers and makes Crawl return. Links already queued stay
+// in the frontier, if there is one.
+func (c *Crawler) Pause() {
+	c.stopOnce.Do(func() { close(c.stop) })
 }
 
-func (c *Crawler) dispatchWork(uri string) {
+// Interrupt stops the crawl like Pause, but leaves the job marked running, as
+// it would be if the process had died, so it is resumed on the next start.
+func (c *Crawler) Interrupt() {
+	c.interrupted.Store(true)
+	c.Pause()
+}
+
+func (c *Crawler) dispatchWork(l Link) {
+	c.inflight.Inc()
 	for {
+		select {
+		case <-c.stop:
+			c.inflight.Dec()
+			return
+		default:
+		}
 		if val := c.currentWorker.Load(); cap(c.workers[val]) > len(c.workers[val]) {
-			c.workers[val] <- uri
+			c.workers[val] <- l
 			c.currentWorker.Store((val + 1) % uint32(len(c.workers)))
 			return
 		} else {
@@ -106,74 +260,157 @@ func (c *Crawler) dispatchWork(uri string) {
 }
 
 func (c *Crawler) buildWorkers(numWorkers uint8) {
-	c.workers = make([]chan string, 0, int(numWorkers))
+	c.workers = make([]chan Link, 0, int(numWorkers))
 
 	for i := 0; i < int(numWorkers); i++ {
-		c.workers = append(c.workers, make(chan string, 1<<16))
+		c.workers = append(c.workers, make(chan Link, 1<<16))
+		c.workersWg.Add(1)
 		go func(ix int) {
+			defer c.workersWg.Done()
 			for c.requestCount.Load() < c.maxRequests {
-				href := <-c.workers[ix]
-				c.findHrefs(href)
+				select {
+				case l := <-c.workers[ix]:
+					c.findHrefs(l)
+					c.release()
+				case <-c.stop:
+					return
+				}
 			}
 		}(i)
 	}
 }
 
 // used within a worker goroutine
-func (c *Crawler) findHrefs(uri string) {
+func (c *Crawler) findHrefs(link Link) {
+	uri := link.URL
 	c.log(uri)
-	if v, _ := c.visited.Get(uri); v {
+	u, err := url.Parse(uri)
+	if err != nil {
+		fmt.Println(err.Error())
+		return
+	}
+	key := canonicalURL(u)
+	if v, _ := c.visited.Get(key); v {
 		return
 	}
 
-	var cli *http.Client = new(http.Client)
-	*cli = *http.DefaultClient
-	c.visited.Set(uri, true)
+	c.visited.Set(key, true)
 
-	res, err := cli.Get(uri)
+	if link.Kind != LinkSeed && c.frontier.fetchedRecently(key) {
+		c.done(key, false)
+		return
+	}
+	rules := c.robots.get(c.ctx, u)
+	if !rules.Allowed(u.RequestURI()) {
+		c.log("robots.txt disallows", uri)
+		c.done(key, false)
+		return
+	}
+	if c.limiter.wait(c.ctx, u.Host, rules.crawlDelay) != nil {
+		return
+	}
+
+	req, err := http.NewRequestWithContext(withLink(c.ctx, link), "GET", uri, nil)
 	if err != nil {
 		fmt.Println(err.Error())
+		c.errorCount.Inc()
+		c.done(key, false)
+		return
+	}
+	req.Header.Set("user-agent", c.userAgent)
+	res, err := c.cli.Do(req)
+	if err != nil {
+		c.fetchError(key, err)
 		return
 	}
+	defer res.Body.Close()
 
 	b, err := io.ReadAll(res.Body)
 	if err != nil {
-		fmt.Println(err.Error())
+		c.fetchError(key, err)
 		return
 	}
-	c.requestCount.Add(1)
+	n := c.requestCount.Add(1)
 
 	if ct := res.Header.Get("content-type"); strings.Contains(ct, "text/") {
 
 		if c.textHandler != nil {
-			c.textHandler(res)
+			c.handle(c.textHandler, res)
 		}
 	} else if strings.Contains(ct, "application/") {
 		if c.applicationHandler != nil {
-			c.applicationHandler(res)
+			c.handle(c.applicationHandler, res)
 		}
 	}
-	if c.requestCount.Load() == c.maxRequests {
-		c.wg.Done()
+	c.done(key, true)
+	// other workers may have counted requests since, so compare this one's
+	if n >= c.maxRequests {
+		c.doneOnce.Do(c.wg.Done)
 		return
 	}
 
-	for _, match := range ptnFindHref.FindAllSubmatch(b, -1) {
-		ms := string(match[1])
-		if len(ms) > 2 && ms[0:2] == "//" {
-			ms = "https://" + ms[2:]
+	if ct := res.Header.Get("content-type"); ct != "" && !strings.Contains(ct, "html") {
+		return
+	}
+	links, baseHref := extractLinks(bytes.NewReader(b))
+	base := documentBase(res.Request.URL, baseHref)
+	for _, l := range links {
+		next, ok := resolveHref(base, l.URL)
+		if !ok {
+			continue
 		}
-		if !c.ignored(ms) {
-			if !strings.Contains(ms, "http") {
-				ms = strings.TrimSuffix(uri, "/") + "/" + strings.TrimPrefix(ms, "/")
-			}
-			if strings.Contains(ms, "http") {
-				c.dispatchWork(ms)
+		l.URL = canonicalURL(next)
+		l.Depth = link.Depth + 1
+		if c.ignored(l.URL) || !c.scope.allowed(next, l.Depth) {
+			continue
+		}
+		if c.linkFilter != nil && !c.linkFilter(l.URL, l) {
+			continue
+		}
+		if v, _ := c.visited.Get(l.URL); !v {
+			if err := c.frontier.push(l); err != nil {
+				c.log("frontier", err.Error())
 			}
+			c.dispatchWork(l)
 		}
 	}
 }
 
+// fetchError records a failed fetch of uri, unless it failed because the crawl
+// is stopping, in which case uri stays in the frontier.
+func (c *Crawler) fetchError(uri string, err error) {
+	if c.ctx.Err() != nil {
+		return
+	}
+	fmt.Println(err.Error())
+	c.errorCount.Inc()
+	c.done(uri, false)
+}
+
+func (c *Crawler) handle(h ResponseHandler, res *http.Response) {
+	if err := h(res); err != nil {
+		c.log("handler", res.Request.URL.String(), err.Error())
+		c.errorCount.Inc()
+		return
+	}
+	c.documentCount.Inc()
+}
+
+// release marks a queued link as handled and finishes the crawl when it was
+// the last one.
+func (c *Crawler) release() {
+	if c.inflight.Dec() == 0 {
+		c.doneOnce.Do(c.wg.Done)
+	}
+}
+
+// done records that uri has been handled in the frontier.
+func (c *Crawler) done(uri string, fetched bool) {
+	if err := c.frontier.done(uri, fetched, c.Stats()); err != nil {
+		c.log("frontier", err.Error())
+	}
+}
+
 func (c *Crawler) SetLogger(l logger.Client) {
 	c.l = l
 }
@@ -0,0 +1,50 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCrawlLimit(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("content-type", "text/html")
+		var page strings.Builder
+		for i := 0; i < 20; i++ {
+			fmt.Fprintf(&page, `<a href="%s/%d">page</a>`, strings.TrimSuffix(r.URL.Path, "/"), i)
+		}
+		w.Write([]byte(page.String()))
+	}))
+	defer srv.Close()
+
+	for i := 0; i < 20; i++ {
+		c, err := NewCrawler(CrawlerParams{Seeds: []string{srv.URL}, Limit: 5, HostRate: 1e6, HostBurst: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		// a slow handler keeps several workers between counting a request and
+		// checking the limit
+		c.SetTextFileHandler(func(*http.Response) error {
+			time.Sleep(10 * time.Millisecond)
+			return nil
+		})
+		done := make(chan error, 1)
+		go func() { done <- c.Crawl(context.Background(), 8) }()
+		select {
+		case err = <-done:
+		case <-time.After(5 * time.Second):
+			c.Pause()
+			t.Fatalf("crawl did not stop at its limit, %d requests", c.Stats().Requests)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := c.Stats().Requests; n < 5 {
+			t.Fatalf("crawl stopped after %d requests", n)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package scraper
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/mrod502/stockscraper/obj"
+	"golang.org/x/net/html"
+)
+
+func NewDuckDuckGoClient(cfg Config) *DuckDuckGoClient {
+	return &DuckDuckGoClient{p: &DParser{}, cfg: cfg, b: newBackoff()}
+}
+
+// DuckDuckGoClient scrapes the javascript free html.duckduckgo.com endpoint.
+type DuckDuckGoClient struct {
+	p   *DParser
+	cfg Config
+	b   *backoff
+}
+
+func (c *DuckDuckGoClient) Scrape(ctx context.Context, req Request) (d []*obj.Document, err error) {
+	if err = c.b.check(EngineDuckDuckGo); err != nil {
+		return nil, err
+	}
+	q, err := c.cfg.Query(req)
+	if err != nil {
+		return nil, err
+	}
+	d, err = c.cfg.paginate(ctx, req, func(page int) ([]*obj.Document, error) {
+		return searchPage(ctx, EngineDuckDuckGo, buildDuckDuckGoUri(q, page), c.p)
+	})
+	c.b.observe(err)
+	for _, doc := range d {
+		doc.Symbols = []string{req.Symbol}
+	}
+	return
+}
+
+// DParser parses DuckDuckGo html result pages. Result titles are
+// <a class="result__a"> links, usually wrapped in a /l/?uddg= redirect.
+type DParser struct {
+}
+
+func (p *DParser) Parse(b []byte) (d []*obj.Document, err error) {
+	d = make([]*obj.Document, 0, 10)
+	z := html.NewTokenizer(bytes.NewReader(b))
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return d, nil
+			}
+			return d, z.Err()
+		case html.StartTagToken:
+			t := z.Token()
+			if t.Data != "a" || !hasClass(t.Attr, "result__a") {
+				continue
+			}
+			href, ok := getHref(t.Attr)
+			if !ok {
+				continue
+			}
+			var doc = new(obj.Document)
+			doc.Item = obj.NewItem(obj.TDocument)
+			if v, err := stripQuery(unwrapDuckDuckGo(href)); err == nil {
+				doc.Source = v
+				doc.ContentType = getLinkContentType(v)
+			}
+			doc.Title = strings.TrimSpace(textUntil(z, "a"))
+			if doc.Source != "" {
+				d = append(d, doc)
+			}
+		}
+	}
+}
+
+// unwrapDuckDuckGo returns the target of a //duckduckgo.com/l/?uddg= redirect
+// link, or href itself.
+func unwrapDuckDuckGo(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return href
+	}
+	if target := u.Query().Get("uddg"); target != "" {
+		return target
+	}
+	if u.Scheme == "" && strings.HasPrefix(href, "//") {
+		return "https:" + href
+	}
+	return href
+}
+
+// textUntil returns the text up to the end tag of the given element.
+func textUntil(z *html.Tokenizer, tag string) string {
+	var sb strings.Builder
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			return sb.String()
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == tag {
+				return sb.String()
+			}
+		case html.TextToken:
+			sb.Write(z.Text())
+		}
+	}
+}
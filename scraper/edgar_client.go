@@ -0,0 +1,294 @@
+package scraper
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+const (
+	edgarTickers     = "https://www.sec.gov/files/company_tickers.json"
+	edgarSubmissions = "https://data.sec.gov/submissions"
+	edgarSearch      = "https://efts.sec.gov/LATEST/search-index"
+	edgarArchives    = "https://www.sec.gov"
+
+	defaultEdgarMaxFilings = 40
+)
+
+var (
+	ErrUnknownSymbol  = errors.New("symbol not found in ticker map")
+	DefaultEdgarForms = []string{"10-K", "10-Q", "8-K"}
+)
+
+// EdgarConfig configures EdgarClient. The URL fields default to the public
+// SEC endpoints and only need to be set to point the client elsewhere.
+type EdgarConfig struct {
+	UserAgent      string   `yaml:"user_agent"` // the SEC asks for "Company Name admin@example.com"
+	Forms          []string `yaml:"forms"`
+	MaxFilings     int      `yaml:"max_filings"`
+	TickerURL      string   `yaml:"ticker_url"`
+	SubmissionsURL string   `yaml:"submissions_url"`
+	SearchURL      string   `yaml:"search_url"`
+	ArchivesURL    string   `yaml:"archives_url"`
+}
+
+// EdgarClient lists SEC filings for a symbol.
+type EdgarClient struct {
+	cfg   EdgarConfig
+	cli   *http.Client
+	l     *sync.Mutex
+	ciks  map[string]string // ticker -> zero padded cik
+	forms map[string]bool
+	b     *backoff
+}
+
+func NewEdgarClient(cfg EdgarConfig) *EdgarClient {
+	if cfg.TickerURL == "" {
+		cfg.TickerURL = edgarTickers
+	}
+	if cfg.SubmissionsURL == "" {
+		cfg.SubmissionsURL = edgarSubmissions
+	}
+	if cfg.SearchURL == "" {
+		cfg.SearchURL = edgarSearch
+	}
+	if cfg.ArchivesURL == "" {
+		cfg.ArchivesURL = edgarArchives
+	}
+	if len(cfg.Forms) == 0 {
+		cfg.Forms = DefaultEdgarForms
+	}
+	if cfg.MaxFilings <= 0 {
+		cfg.MaxFilings = defaultEdgarMaxFilings
+	}
+	e := &EdgarClient{cfg: cfg, cli: http.DefaultClient, l: &sync.Mutex{}, forms: make(map[string]bool), b: newBackoff()}
+	for _, f := range cfg.Forms {
+		e.forms[strings.ToUpper(f)] = true
+	}
+	return e
+}
+
+// Scrape returns the recent filings of the requested symbol, plus filings
+// found by full text search for the symbol, whose documents have the
+// requested file type. Query templates don't apply to EDGAR. Full text
+// search only adds to the submissions, so if it fails the submissions are
+// returned alone.
+func (e *EdgarClient) Scrape(ctx context.Context, req Request) (d []*obj.Document, err error) {
+	if err = e.b.check(EngineEdgar); err != nil {
+		return nil, err
+	}
+	var searchErr error
+	defer func() {
+		if err == nil && searchErr != nil {
+			// a block on search still backs off the engine
+			e.b.observe(searchErr)
+			return
+		}
+		e.b.observe(err)
+	}()
+
+	symbol := strings.ToUpper(req.Symbol)
+	cik, err := e.CIK(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+	d, err = e.submissions(ctx, symbol, cik)
+	if err != nil {
+		return nil, err
+	}
+	found, searchErr := e.search(ctx, symbol, cik)
+
+	seen := make(map[string]bool, len(d))
+	out := make([]*obj.Document, 0, len(d)+len(found))
+	for _, doc := range append(d, found...) {
+		if seen[doc.Source] || !hasFileType(doc.Source, req.FileType) {
+			continue
+		}
+		seen[doc.Source] = true
+		out = append(out, doc)
+	}
+	if len(out) > e.cfg.MaxFilings {
+		out = out[:e.cfg.MaxFilings]
+	}
+	if req.MaxResults > 0 && len(out) > req.MaxResults {
+		out = out[:req.MaxResults]
+	}
+	return out, nil
+}
+
+// CIK resolves symbol to its zero padded central index key.
+func (e *EdgarClient) CIK(ctx context.Context, symbol string) (string, error) {
+	e.l.Lock()
+	defer e.l.Unlock()
+	if e.ciks == nil {
+		var tickers map[string]struct {
+			CIK    int    `json:"cik_str"`
+			Ticker string `json:"ticker"`
+		}
+		if err := e.getJSON(ctx, e.cfg.TickerURL, &tickers); err != nil {
+			return "", err
+		}
+		e.ciks = make(map[string]string, len(tickers))
+		for _, t := range tickers {
+			e.ciks[strings.ToUpper(t.Ticker)] = fmt.Sprintf("%010d", t.CIK)
+		}
+	}
+	cik, ok := e.ciks[strings.ToUpper(symbol)]
+	if !ok {
+		return "", ErrUnknownSymbol
+	}
+	return cik, nil
+}
+
+type submissionsResponse struct {
+	Name    string `json:"name"`
+	Filings struct {
+		Recent struct {
+			AccessionNumber       []string `json:"accessionNumber"`
+			FilingDate            []string `json:"filingDate"`
+			Form                  []string `json:"form"`
+			PrimaryDocument       []string `json:"primaryDocument"`
+			PrimaryDocDescription []string `json:"primaryDocDescription"`
+		} `json:"recent"`
+	} `json:"filings"`
+}
+
+func (e *EdgarClient) submissions(ctx context.Context, symbol, cik string) ([]*obj.Document, error) {
+	var sub submissionsResponse
+	if err := e.getJSON(ctx, e.cfg.SubmissionsURL+"/CIK"+cik+".json", &sub); err != nil {
+		return nil, err
+	}
+	r := sub.Filings.Recent
+	d := make([]*obj.Document, 0)
+	for i := range r.AccessionNumber {
+		if i >= len(r.Form) || i >= len(r.PrimaryDocument) || i >= len(r.FilingDate) {
+			break
+		}
+		if !e.forms[strings.ToUpper(r.Form[i])] || r.PrimaryDocument[i] == "" {
+			continue
+		}
+		title := sub.Name + " " + r.Form[i]
+		if i < len(r.PrimaryDocDescription) && r.PrimaryDocDescription[i] != "" {
+			title = sub.Name + " " + r.PrimaryDocDescription[i]
+		}
+		d = append(d, e.newDocument(symbol, cik, r.AccessionNumber[i], r.PrimaryDocument[i], r.Form[i], r.FilingDate[i], title))
+	}
+	return d, nil
+}
+
+type searchResponse struct {
+	Hits struct {
+		Hits []struct {
+			Id     string `json:"_id"` // accession number:file name
+			Source struct {
+				DisplayNames []string `json:"display_names"`
+				Form         string   `json:"form"`
+				FileType     string   `json:"file_type"`
+				FileDate     string   `json:"file_date"`
+				Adsh         string   `json:"adsh"`
+			} `json:"_source"`
+		} `json:"hits"`
+	} `json:"hits"`
+}
+
+func (e *EdgarClient) search(ctx context.Context, symbol, cik string) ([]*obj.Document, error) {
+	q := url.Values{}
+	q.Set("q", `"`+symbol+`"`)
+	q.Set("ciks", cik)
+	q.Set("forms", strings.Join(e.cfg.Forms, ","))
+	var res searchResponse
+	if err := e.getJSON(ctx, e.cfg.SearchURL+"?"+q.Encode(), &res); err != nil {
+		return nil, err
+	}
+	d := make([]*obj.Document, 0, len(res.Hits.Hits))
+	for _, h := range res.Hits.Hits {
+		adsh, file, ok := strings.Cut(h.Id, ":")
+		if !ok || !e.forms[strings.ToUpper(h.Source.Form)] {
+			continue
+		}
+		if h.Source.Adsh != "" {
+			adsh = h.Source.Adsh
+		}
+		title := h.Source.Form + " " + h.Source.FileType
+		if len(h.Source.DisplayNames) > 0 {
+			title = h.Source.DisplayNames[0] + " " + title
+		}
+		d = append(d, e.newDocument(symbol, cik, adsh, file, h.Source.Form, h.Source.FileDate, title))
+	}
+	return d, nil
+}
+
+func (e *EdgarClient) newDocument(symbol, cik, accession, file, form, date, title string) *obj.Document {
+	doc := &obj.Document{
+		Item:    obj.NewItem(obj.TDocument),
+		Title:   strings.TrimSpace(title),
+		Symbols: []string{symbol},
+		Source: fmt.Sprintf("%s/Archives/edgar/data/%s/%s/%s", e.cfg.ArchivesURL,
+			strings.TrimLeft(cik, "0"), strings.ReplaceAll(accession, "-", ""), file),
+		ContentType: edgarContentType(file),
+		Type:        form,
+	}
+	doc.PostedDate, _ = time.Parse("2006-01-02", date)
+	return doc
+}
+
+func (e *EdgarClient) getJSON(ctx context.Context, uri string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("accept", "application/json")
+	if e.cfg.UserAgent != "" {
+		req.Header.Set("user-agent", e.cfg.UserAgent)
+	}
+	res, err := e.cli.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(res.Body)
+		if err = detectBlock(EngineEdgar, res, b); err != nil {
+			return err
+		}
+		return fmt.Errorf("edgar: %s returned %s", uri, res.Status)
+	}
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+func edgarContentType(file string) string {
+	switch strings.ToLower(path.Ext(file)) {
+	case ".htm", ".html":
+		return "text/html"
+	case ".xml":
+		return "text/xml"
+	case ".txt":
+		return "text/plain"
+	case ".pdf":
+		return "application/pdf"
+	default:
+		return ""
+	}
+}
+
+// hasFileType reports whether uri names a file of the given /scrape filetype.
+func hasFileType(uri, ftype string) bool {
+	ext := strings.TrimPrefix(strings.ToLower(path.Ext(uri)), ".")
+	switch ftype {
+	case "", ext:
+		return true
+	case "html":
+		return ext == "htm"
+	}
+	return false
+}
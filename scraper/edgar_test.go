@@ -0,0 +1,91 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+func edgarFixtures(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/files/company_tickers.json", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/edgar/company_tickers.json")
+	})
+	mux.HandleFunc("/submissions/CIK0000320193.json", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/edgar/CIK0000320193.json")
+	})
+	mux.HandleFunc("/search-index", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("ciks") != "0000320193" {
+			t.Errorf("unexpected search query %s", r.URL.RawQuery)
+		}
+		http.ServeFile(w, r, "testdata/edgar/search.json")
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestEdgarClient(t *testing.T) {
+	srv := edgarFixtures(t)
+	e := NewEdgarClient(EdgarConfig{
+		UserAgent:      "stockscraper test@example.com",
+		TickerURL:      srv.URL + "/files/company_tickers.json",
+		SubmissionsURL: srv.URL + "/submissions",
+		SearchURL:      srv.URL + "/search-index",
+		ArchivesURL:    "https://www.sec.gov",
+	})
+
+	docs, err := e.Scrape(context.Background(), Request{Symbol: "aapl", FileType: "html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the form 4 is filtered out and the 10-K found by search is a duplicate
+	if len(docs) != 3 {
+		t.Fatalf("expected 3 filings, got %d", len(docs))
+	}
+	tenK := docs[0]
+	if tenK.Type != obj.Type10K || !tenK.PostedDate.Equal(time.Date(2023, 11, 3, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected filing %+v", tenK)
+	}
+	if want := "https://www.sec.gov/Archives/edgar/data/320193/000032019323000106/aapl-20230930.htm"; tenK.Source != want {
+		t.Fatalf("source = %s, want %s", tenK.Source, want)
+	}
+	if docs[2].Type != obj.Type8K || docs[2].Symbols[0] != "AAPL" {
+		t.Fatalf("unexpected search hit %+v", docs[2])
+	}
+
+	if _, err = e.Scrape(context.Background(), Request{Symbol: "ZZZZ", FileType: "html"}); err != ErrUnknownSymbol {
+		t.Fatalf("expected ErrUnknownSymbol, got %v", err)
+	}
+}
+
+func TestEdgarSearchFailure(t *testing.T) {
+	srv := edgarFixtures(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/search-index", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	})
+	failing := httptest.NewServer(mux)
+	t.Cleanup(failing.Close)
+	e := NewEdgarClient(EdgarConfig{
+		TickerURL:      srv.URL + "/files/company_tickers.json",
+		SubmissionsURL: srv.URL + "/submissions",
+		SearchURL:      failing.URL + "/search-index",
+		ArchivesURL:    "https://www.sec.gov",
+	})
+
+	docs, err := e.Scrape(context.Background(), Request{Symbol: "AAPL", FileType: "html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 || docs[0].Type != obj.Type10K {
+		t.Fatalf("expected the 2 submitted filings, got %+v", docs)
+	}
+	if err = e.b.check(EngineEdgar); err != nil {
+		t.Fatalf("search error backed off the engine: %v", err)
+	}
+}
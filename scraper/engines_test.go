@@ -0,0 +1,69 @@
+package scraper
+
+import (
+	"net/url"
+	"os"
+	"testing"
+)
+
+func TestBingParser(t *testing.T) {
+	b, err := os.ReadFile("testdata/bing.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := (&BParser{}).Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 4 {
+		t.Fatalf("expected 4 results, got %d", len(docs))
+	}
+	if docs[0].Source != "https://example.com/research/NVDA-initiation.pdf" || docs[0].Title != "NVIDIA Corp: Initiating Coverage" {
+		t.Fatalf("unexpected result %+v", docs[0])
+	}
+	if docs[0].PostedDate.Year() != 2020 || docs[1].PostedDate.Year() != 2021 {
+		t.Fatal("posted dates not parsed")
+	}
+	// /ck/a redirects resolve to their targets
+	if docs[2].Source != "https://investor.nvidia.com/files/doc_financials/2023/Q4FY23-CFO-Commentary.pdf" || docs[2].ContentType != "application/pdf" {
+		t.Fatalf("unexpected redirect result %+v", docs[2])
+	}
+	if docs[3].Source != "https://www.sec.gov/Archives/edgar/data/1045810/000104581023000017/nvda-20230129.htm" {
+		t.Fatalf("unexpected redirect result %+v", docs[3])
+	}
+}
+
+func TestDuckDuckGoParser(t *testing.T) {
+	b, err := os.ReadFile("testdata/duckduckgo.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs, err := (&DParser{}).Parse(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(docs))
+	}
+	if docs[0].Source != "https://example.com/research/NVDA-note.pdf" || docs[0].Title != "NVDA equity research note" {
+		t.Fatalf("unexpected result %+v", docs[0])
+	}
+	if docs[1].ContentType != "application/pdf" {
+		t.Fatalf("unexpected content type %s", docs[1].ContentType)
+	}
+}
+
+func TestBuildUris(t *testing.T) {
+	q := `"AT&T" C# S+P 100% filetype:pdf`
+	for name, build := range map[string]func(string, int) string{
+		EngineGoogle: buildGoogleUri, EngineBing: buildBingUri, EngineDuckDuckGo: buildDuckDuckGoUri,
+	} {
+		u, err := url.Parse(build(q, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := u.Query().Get("q"); got != q || u.Fragment != "" {
+			t.Errorf("%s: q = %q in %s", name, got, u)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package scraper
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/mrod502/stockscraper/db"
+)
+
+// Frontier keys. Pending links and visited URLs are stored one key per URL
+// under the job so that a crawl can be resumed from where it stopped.
+// Fetch times are shared by all jobs.
+const (
+	prefixCrawlJob     = "crawl:job:"
+	prefixCrawlQueue   = "crawl:q:"
+	prefixCrawlVisited = "crawl:v:"
+	prefixCrawlFetched = "crawl:f:"
+
+	DefaultRecrawlAfter = 24 * time.Hour
+)
+
+const (
+	CrawlRunning  = "running"
+	CrawlPaused   = "paused"
+	CrawlDone     = "done"
+	CrawlCanceled = "canceled"
+)
+
+var (
+	ErrCrawlJobNotFound = errors.New("crawl job not found")
+)
+
+// CrawlJob is the stored state of a crawl.
+type CrawlJob struct {
+	Id     string
+	Params CrawlerParams
+	Status string
+	CrawlStats
+	Started time.Time
+	Updated time.Time
+}
+
+// Frontier persists a crawl job's pending links and visited URLs.
+type Frontier struct {
+	db      *db.DB
+	l       sync.Mutex
+	job     CrawlJob
+	recrawl time.Duration
+}
+
+func NewFrontier(d *db.DB, params CrawlerParams) *Frontier {
+	f := &Frontier{db: d, job: CrawlJob{Id: params.Job, Params: params}}
+	switch {
+	case params.RecrawlAfter < 0:
+		f.recrawl = 0
+	case params.RecrawlAfter == 0:
+		f.recrawl = DefaultRecrawlAfter
+	default:
+		f.recrawl = time.Duration(params.RecrawlAfter * float64(time.Hour))
+	}
+	return f
+}
+
+// LoadCrawlJob returns the stored state of job id.
+func LoadCrawlJob(d *db.DB, id string) (job CrawlJob, err error) {
+	if err = d.Get(prefixCrawlJob+id, &job); errors.Is(err, badger.ErrKeyNotFound) {
+		err = ErrCrawlJobNotFound
+	}
+	return
+}
+
+// CrawlJobs returns every stored crawl job.
+func CrawlJobs(d *db.DB) (jobs []CrawlJob, err error) {
+	jobs = make([]CrawlJob, 0)
+	err = d.Scan(prefixCrawlJob, func(key string, decode func(v interface{}) error) error {
+		var job CrawlJob
+		if err := decode(&job); err != nil {
+			return err
+		}
+		jobs = append(jobs, job)
+		return nil
+	})
+	return
+}
+
+func queueKey(job, uri string) string   { return prefixCrawlQueue + job + ":" + uri }
+func visitedKey(job, uri string) string { return prefixCrawlVisited + job + ":" + uri }
+
+// start marks the job running and returns the links to crawl, the URLs
+// already visited and the progress made so far. A new job starts from its
+// seeds, a paused or interrupted one from its stored queue.
+func (f *Frontier) start(seeds []Link) (pending []Link, visited []string, st CrawlStats, err error) {
+	f.l.Lock()
+	defer f.l.Unlock()
+	prev, err := LoadCrawlJob(f.db, f.job.Id)
+	switch {
+	case err == nil && (prev.Status == CrawlRunning || prev.Status == CrawlPaused):
+		f.job = prev
+		if pending, err = f.queued(); err != nil {
+			return
+		}
+		visited = f.visited()
+		st = prev.CrawlStats
+	case err == nil || errors.Is(err, ErrCrawlJobNotFound):
+		f.job.Started = time.Now()
+		f.job.CrawlStats = CrawlStats{}
+		pending = seeds
+		kv := make(map[string]any, len(seeds))
+		for _, l := range seeds {
+			kv[queueKey(f.job.Id, l.URL)] = l
+		}
+		if err = f.db.PutMany(kv); err != nil {
+			return
+		}
+	default:
+		return
+	}
+	err = f.setStatus(CrawlRunning)
+	return
+}
+
+func (f *Frontier) queued() ([]Link, error) {
+	links := make([]Link, 0)
+	err := f.db.Scan(prefixCrawlQueue+f.job.Id+":", func(key string, decode func(v interface{}) error) error {
+		var l Link
+		if err := decode(&l); err != nil {
+			return err
+		}
+		links = append(links, l)
+		return nil
+	})
+	return links, err
+}
+
+func (f *Frontier) visited() []string {
+	prefix := prefixCrawlVisited + f.job.Id + ":"
+	keys := f.db.Keys(prefix)
+	for i, k := range keys {
+		keys[i] = strings.TrimPrefix(k, prefix)
+	}
+	return keys
+}
+
+// push adds l to the job's queue.
+func (f *Frontier) push(l Link) error {
+	if f == nil {
+		return nil
+	}
+	return f.db.Put(queueKey(f.job.Id, l.URL), l)
+}
+
+// fetchedRecently reports whether any crawl fetched uri within the recrawl
+// window.
+func (f *Frontier) fetchedRecently(uri string) bool {
+	if f == nil || f.recrawl == 0 {
+		return false
+	}
+	var at time.Time
+	if err := f.db.Get(prefixCrawlFetched+uri, &at); err != nil {
+		return false
+	}
+	return time.Since(at) < f.recrawl
+}
+
+// done moves uri from the queue to the visited set and records the crawl's
+// progress. fetched is set when the crawler made a request for it.
+func (f *Frontier) done(uri string, fetched bool, st CrawlStats) error {
+	if f == nil {
+		return nil
+	}
+	f.l.Lock()
+	if f.job.Status != CrawlRunning {
+		// a worker finishing after the job stopped
+		f.l.Unlock()
+		return nil
+	}
+	kv := map[string]any{visitedKey(f.job.Id, uri): true}
+	if fetched {
+		now := time.Now()
+		kv[prefixCrawlFetched+uri] = now
+		f.job.CrawlStats = st
+		f.job.Updated = now
+		kv[prefixCrawlJob+f.job.Id] = f.job
+	}
+	f.l.Unlock()
+	if err := f.db.PutMany(kv); err != nil {
+		return err
+	}
+	return f.db.Delete(queueKey(f.job.Id, uri))
+}
+
+// pause keeps the queue so the job can be resumed.
+func (f *Frontier) pause() error {
+	if f == nil {
+		return nil
+	}
+	f.l.Lock()
+	defer f.l.Unlock()
+	return f.setStatus(CrawlPaused)
+}
+
+// save stores the job's progress without changing its status.
+func (f *Frontier) save() error {
+	if f == nil {
+		return nil
+	}
+	f.l.Lock()
+	defer f.l.Unlock()
+	return f.setStatus(f.job.Status)
+}
+
+// finish marks the job done or canceled and drops its queue and visited set.
+// Fetch times are kept for later crawls.
+func (f *Frontier) finish(status string) error {
+	if f == nil {
+		return nil
+	}
+	f.l.Lock()
+	defer f.l.Unlock()
+	keys := append(f.db.Keys(prefixCrawlQueue+f.job.Id+":"), f.db.Keys(prefixCrawlVisited+f.job.Id+":")...)
+	if err := f.db.DeleteMany(keys); err != nil {
+		return err
+	}
+	f.job.Queued = 0
+	return f.setStatus(status)
+}
+
+// setStatus must be called with f.l held.
+func (f *Frontier) setStatus(status string) error {
+	f.job.Status = status
+	f.job.Updated = time.Now()
+	return f.db.Put(prefixCrawlJob+f.job.Id, f.job)
+}
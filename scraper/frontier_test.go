@@ -0,0 +1,186 @@
+package scraper
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/mrod502/stockscraper/db"
+)
+
+func testDB(t *testing.T) *db.DB {
+	d, err := db.New(db.Config{BadgerOpts: badger.DefaultOptions("").WithInMemory(true).WithLogger(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func TestFrontierResume(t *testing.T) {
+	d := testDB(t)
+	params := CrawlerParams{Job: "job1"}
+	seed := Link{URL: "https://example.com/", Kind: LinkSeed}
+	next := Link{URL: "https://example.com/ir", Kind: LinkAnchor, Text: "Investors", Depth: 1}
+
+	f := NewFrontier(d, params)
+	pending, _, _, err := f.start([]Link{seed})
+	if err != nil || len(pending) != 1 {
+		t.Fatalf("start = %v, %v", pending, err)
+	}
+	if err = f.push(next); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.done(seed.URL, true, CrawlStats{Requests: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.pause(); err != nil {
+		t.Fatal(err)
+	}
+
+	f = NewFrontier(d, params)
+	pending, visited, st, err := f.start([]Link{seed})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0] != next {
+		t.Fatalf("pending = %+v", pending)
+	}
+	if len(visited) != 1 || visited[0] != seed.URL || st.Requests != 1 {
+		t.Fatalf("visited = %v, stats = %+v", visited, st)
+	}
+	if !f.fetchedRecently(seed.URL) || f.fetchedRecently(next.URL) {
+		t.Fatal("unexpected fetch times")
+	}
+
+	if err = f.finish(CrawlDone); err != nil {
+		t.Fatal(err)
+	}
+	job, err := LoadCrawlJob(d, "job1")
+	if err != nil || job.Status != CrawlDone {
+		t.Fatalf("job = %+v, %v", job, err)
+	}
+	if keys := d.Keys(prefixCrawlQueue); len(keys) != 0 {
+		t.Fatalf("queue not cleared: %v", keys)
+	}
+}
+
+func TestCrawlFrontier(t *testing.T) {
+	var fetched []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			http.NotFound(w, r)
+			return
+		}
+		fetched = append(fetched, r.URL.Path)
+		w.Header().Set("content-type", "text/html")
+		switch r.URL.Path {
+		case "/":
+			w.Write([]byte(`<a href="/a">a</a> <a href="/b">b</a>`))
+		default:
+			w.Write([]byte(`<a href="/">home</a>`))
+		}
+	}))
+	defer srv.Close()
+
+	d := testDB(t)
+	crawl := func(job string) {
+		c, err := NewCrawler(CrawlerParams{Job: job, Seeds: []string{srv.URL}, Limit: 10, HostRate: 1000, HostBurst: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.SetTextFileHandler(func(*http.Response) error { return nil })
+		c.SetFrontier(NewFrontier(d, CrawlerParams{Job: job}))
+		if err = c.Crawl(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	crawl("first")
+	if len(fetched) != 3 {
+		t.Fatalf("first crawl fetched %v", fetched)
+	}
+	job, _ := LoadCrawlJob(d, "first")
+	if job.Status != CrawlDone || job.Requests != 3 || job.Documents != 3 {
+		t.Fatalf("job = %+v", job)
+	}
+
+	// a later crawl only refetches its seed
+	fetched = nil
+	crawl("second")
+	if len(fetched) != 1 || fetched[0] != "/" {
+		t.Fatalf("second crawl fetched %v", fetched)
+	}
+}
+
+func TestCrawlCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("content-type", "text/html")
+		w.Write([]byte(`<a href="/slow">slow</a>`))
+	}))
+	defer srv.Close()
+
+	d := testDB(t)
+	c, err := NewCrawler(CrawlerParams{Job: "cancel", Seeds: []string{srv.URL}, Limit: 10, HostRate: 1000, HostBurst: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetFrontier(NewFrontier(d, CrawlerParams{Job: "cancel"}))
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	if err = c.Crawl(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	job, _ := LoadCrawlJob(d, "cancel")
+	if job.Status != CrawlCanceled || job.Requests != 1 {
+		t.Fatalf("job = %+v", job)
+	}
+}
+
+func TestCrawlInterrupt(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("content-type", "text/html")
+		w.Write([]byte(`<a href="/slow">slow</a>`))
+	}))
+	defer srv.Close()
+
+	d := testDB(t)
+	for _, tc := range []struct {
+		job    string
+		stop   func(*Crawler)
+		status string
+	}{
+		{"pause", (*Crawler).Pause, CrawlPaused},
+		{"interrupt", (*Crawler).Interrupt, CrawlRunning},
+	} {
+		c, err := NewCrawler(CrawlerParams{Seeds: []string{srv.URL}, Limit: 10, HostRate: 1000, HostBurst: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f := NewFrontier(d, CrawlerParams{Job: tc.job})
+		c.SetFrontier(f)
+		time.AfterFunc(100*time.Millisecond, func() { tc.stop(c) })
+		if err = c.Crawl(context.Background(), 1); err != nil {
+			t.Fatal(err)
+		}
+		job, _ := LoadCrawlJob(d, tc.job)
+		if job.Status != tc.status || job.Errors != 0 {
+			t.Fatalf("%s: job = %+v", tc.job, job)
+		}
+		// the aborted fetch is still queued for the next run
+		if pending, err := NewFrontier(d, CrawlerParams{Job: tc.job}).queued(); err != nil || len(pending) != 1 || pending[0].URL != srv.URL+"/slow" {
+			t.Fatalf("%s: pending = %+v, %v", tc.job, pending, err)
+		}
+	}
+}
@@ -1,43 +1,35 @@
 package scraper
 
 import (
-	"fmt"
-	"io"
-	"net/http"
-
+	"context"
 	"github.com/mrod502/stockscraper/obj"
 )
 
-func NewGoogleClient() *GoogleClient {
-	return &GoogleClient{p: &GParser{}}
+func NewGoogleClient(cfg Config) *GoogleClient {
+	return &GoogleClient{p: &GParser{}, cfg: cfg, b: newBackoff()}
 }
 
 type GoogleClient struct {
-	p *GParser
+	p   *GParser
+	cfg Config
+	b   *backoff
 }
 
-func (g *GoogleClient) Scrape(symbol, ftype string) (d []*obj.Document, err error) {
-
-	uri := buildGoogleUri(fmt.Sprintf("%s equity research filetype:%s", symbol, ftype))
-	r, err := http.NewRequest("GET", uri, nil)
-	if err != nil {
-		return nil, err
-	}
-	setGoogleHeaders(r)
-
-	res, err := http.DefaultClient.Do(r)
-	if err != nil {
+func (g *GoogleClient) Scrape(ctx context.Context, req Request) (d []*obj.Document, err error) {
+	if err = g.b.check(EngineGoogle); err != nil {
 		return nil, err
 	}
-	b, err := io.ReadAll(res.Body)
+	q, err := g.cfg.Query(req)
 	if err != nil {
 		return nil, err
 	}
-
-	d, err = g.p.Parse(b)
+	d, err = g.cfg.paginate(ctx, req, func(page int) ([]*obj.Document, error) {
+		return searchPage(ctx, EngineGoogle, buildGoogleUri(q, page), g.p)
+	})
+	g.b.observe(err)
 	for _, doc := range d {
 		if doc != nil {
-			doc.Symbols = []string{symbol}
+			doc.Symbols = []string{req.Symbol}
 		}
 	}
 	return
@@ -0,0 +1,187 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const maxAnchorText = 256
+
+type LinkKind uint8
+
+const (
+	LinkSeed    LinkKind = iota // a crawl seed, not found on a page
+	LinkAnchor                  // <a href>, <area href>
+	LinkFrame                   // <iframe src>, <frame src>
+	LinkEmbed                   // <embed src>, <object data>
+	LinkFeed                    // <link rel=alternate> RSS / Atom feed
+	LinkRefresh                 // <meta http-equiv=refresh> redirect
+)
+
+func (k LinkKind) String() string {
+	switch k {
+	case LinkSeed:
+		return "seed"
+	case LinkAnchor:
+		return "anchor"
+	case LinkFrame:
+		return "frame"
+	case LinkEmbed:
+		return "embed"
+	case LinkFeed:
+		return "feed"
+	case LinkRefresh:
+		return "refresh"
+	}
+	return "unknown"
+}
+
+// Link is a URL found on a page. Text is the anchor text for LinkAnchor and
+// the title attribute, if any, for the other kinds.
+type Link struct {
+	URL   string
+	Kind  LinkKind
+	Text  string
+	Depth int // links followed from a seed to get here
+}
+
+type linkKey struct{}
+
+// LinkFromResponse returns the link the crawler followed to get res.
+func LinkFromResponse(res *http.Response) (Link, bool) {
+	if res == nil || res.Request == nil {
+		return Link{}, false
+	}
+	l, ok := res.Request.Context().Value(linkKey{}).(Link)
+	return l, ok
+}
+
+func withLink(ctx context.Context, l Link) context.Context {
+	return context.WithValue(ctx, linkKey{}, l)
+}
+
+// extractLinks tokenizes an html page and returns the links on it, unresolved,
+// along with the page's <base href> if it has one.
+func extractLinks(r io.Reader) (links []Link, base string) {
+	z := html.NewTokenizer(r)
+	anchor := -1 // index in links of the <a> whose text is being read
+	var text strings.Builder
+	closeAnchor := func() {
+		if anchor >= 0 {
+			links[anchor].Text = strings.Join(strings.Fields(text.String()), " ")
+			links[anchor].Text = truncate(links[anchor].Text, maxAnchorText)
+			anchor = -1
+			text.Reset()
+		}
+	}
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			closeAnchor()
+			return
+		case html.TextToken:
+			if anchor >= 0 && text.Len() < maxAnchorText*2 {
+				text.Write(z.Text())
+				text.WriteByte(' ')
+			}
+		case html.EndTagToken:
+			if name, _ := z.TagName(); string(name) == "a" {
+				closeAnchor()
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			attr := func(key string) string {
+				for _, a := range t.Attr {
+					if a.Key == key {
+						return strings.TrimSpace(a.Val)
+					}
+				}
+				return ""
+			}
+			var l Link
+			switch t.Data {
+			case "a":
+				closeAnchor()
+				l = Link{URL: attr("href"), Kind: LinkAnchor}
+				if l.URL != "" && tt == html.StartTagToken {
+					anchor = len(links)
+				}
+			case "area":
+				l = Link{URL: attr("href"), Kind: LinkAnchor, Text: attr("alt")}
+			case "iframe", "frame":
+				l = Link{URL: attr("src"), Kind: LinkFrame}
+			case "embed":
+				l = Link{URL: attr("src"), Kind: LinkEmbed}
+			case "object":
+				l = Link{URL: attr("data"), Kind: LinkEmbed}
+			case "link":
+				if isFeedLink(attr("rel"), attr("type")) {
+					l = Link{URL: attr("href"), Kind: LinkFeed}
+				}
+			case "meta":
+				if strings.EqualFold(attr("http-equiv"), "refresh") {
+					l = Link{URL: refreshURL(attr("content")), Kind: LinkRefresh}
+				}
+			case "base":
+				if base == "" {
+					base = attr("href")
+				}
+			case "img":
+				// an image inside a link describes it when there is no text
+				if anchor >= 0 {
+					text.WriteString(attr("alt"))
+					text.WriteByte(' ')
+				}
+			}
+			if l.URL == "" {
+				continue
+			}
+			if l.Text == "" {
+				l.Text = attr("title")
+			}
+			links = append(links, l)
+		}
+	}
+}
+
+// truncate returns at most the first n bytes of s without splitting a rune.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+func isFeedLink(rel, typ string) bool {
+	typ = strings.ToLower(typ)
+	for _, v := range strings.Fields(strings.ToLower(rel)) {
+		if v == "alternate" {
+			return strings.Contains(typ, "rss") || strings.Contains(typ, "atom")
+		}
+	}
+	return false
+}
+
+// refreshURL returns the target of a meta refresh content attribute, e.g.
+// `5; url='/next'`.
+func refreshURL(content string) string {
+	_, target, ok := strings.Cut(content, ";")
+	if !ok {
+		return ""
+	}
+	target = strings.TrimSpace(target)
+	if len(target) > 3 && strings.EqualFold(target[:3], "url") {
+		target = strings.TrimSpace(target[3:])
+		target = strings.TrimSpace(strings.TrimPrefix(target, "="))
+	}
+	return strings.Trim(target, `"'`)
+}
@@ -0,0 +1,53 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+const testLinksPage = `<html><head>
+<base href='/filings/'>
+<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+<link rel="stylesheet" href="/style.css">
+<meta http-equiv="Refresh" content="0; URL='/moved'">
+</head><body>
+<a href='q3.html'>Q3 2022
+  <b>Earnings</b> Call Transcript</a>
+<a href=annual.pdf title="Form 10-K"><img src="x.png" alt="10-K"></a>
+<a name="top">no link</a>
+<iframe src="//player.example.com/v/1"></iframe>
+<embed src="deck.pdf" type="application/pdf">
+</body></html>`
+
+func TestExtractLinks(t *testing.T) {
+	links, base := extractLinks(strings.NewReader(testLinksPage))
+	if base != "/filings/" {
+		t.Fatalf("base = %q", base)
+	}
+	want := []Link{
+		{URL: "/feed.xml", Kind: LinkFeed},
+		{URL: "/moved", Kind: LinkRefresh},
+		{URL: "q3.html", Kind: LinkAnchor, Text: "Q3 2022 Earnings Call Transcript"},
+		{URL: "annual.pdf", Kind: LinkAnchor, Text: "10-K"},
+		{URL: "//player.example.com/v/1", Kind: LinkFrame},
+		{URL: "deck.pdf", Kind: LinkEmbed},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("got %d links: %+v", len(links), links)
+	}
+	for i, v := range want {
+		if links[i] != v {
+			t.Fatalf("link %d = %+v, want %+v", i, links[i], v)
+		}
+	}
+}
+
+func TestExtractLinksLongText(t *testing.T) {
+	// 255 bytes of ASCII puts the limit inside the two byte é
+	page := `<a href="/r.pdf">` + strings.Repeat("a", maxAnchorText-1) + `é rest</a>`
+	links, _ := extractLinks(strings.NewReader(page))
+	if len(links) != 1 || !utf8.ValidString(links[0].Text) || links[0].Text != strings.Repeat("a", maxAnchorText-1) {
+		t.Fatalf("links = %+v", links)
+	}
+}
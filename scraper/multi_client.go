@@ -0,0 +1,148 @@
+package scraper
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+// EngineErrors holds the errors of the engines that failed during a
+// MultiClient scrape, by engine name.
+type EngineErrors map[string]error
+
+func (e EngineErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, 0, len(e))
+	for _, name := range names {
+		msgs = append(msgs, name+": "+e[name].Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+type namedClient struct {
+	name string
+	c    Client
+}
+
+// MultiClient queries several engines concurrently and merges their results.
+// Results found by more than one engine are returned once, with every engine
+// that found them listed in Document.Engines.
+type MultiClient struct {
+	engines []namedClient
+}
+
+// NewMultiClient returns a MultiClient over the engines enabled in cfg.
+func NewMultiClient(cfg Config) (*MultiClient, error) {
+	m := &MultiClient{}
+	for _, name := range cfg.EnabledEngines() {
+		c, err := NewClient(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		m.Add(name, c)
+	}
+	return m, nil
+}
+
+func (m *MultiClient) Add(name string, c Client) {
+	m.engines = append(m.engines, namedClient{name: name, c: c})
+}
+
+// Scrape returns the merged results of every engine. The error is an
+// EngineErrors when some engines failed; results of the other engines are
+// still returned.
+func (m *MultiClient) Scrape(ctx context.Context, r Request) ([]*obj.Document, error) {
+	results := make([][]*obj.Document, len(m.engines))
+	errs := make([]error, len(m.engines))
+	wg := &sync.WaitGroup{}
+	for i, e := range m.engines {
+		wg.Add(1)
+		go func(i int, e namedClient) {
+			defer wg.Done()
+			results[i], errs[i] = e.c.Scrape(ctx, r)
+		}(i, e)
+	}
+	wg.Wait()
+
+	var engineErrs = make(EngineErrors)
+	for i, err := range errs {
+		if err != nil {
+			engineErrs[m.engines[i].name] = err
+		}
+	}
+	d := m.merge(results)
+	if r.MaxResults > 0 && len(d) > r.MaxResults {
+		d = d[:r.MaxResults]
+	}
+	if len(engineErrs) > 0 {
+		return d, engineErrs
+	}
+	return d, nil
+}
+
+// merge de-duplicates results by canonical source, keeping engine order.
+func (m *MultiClient) merge(results [][]*obj.Document) []*obj.Document {
+	d := make([]*obj.Document, 0)
+	seen := make(map[string]*obj.Document)
+	for i, res := range results {
+		name := m.engines[i].name
+		for _, doc := range res {
+			if doc == nil || doc.Source == "" {
+				continue
+			}
+			key := obj.GetSignature([]byte(canonicalSource(doc.Source)))
+			prev, ok := seen[key]
+			if !ok {
+				doc.Engines = []string{name}
+				seen[key] = doc
+				d = append(d, doc)
+				continue
+			}
+			if !contains(prev.Engines, name) {
+				prev.Engines = append(prev.Engines, name)
+			}
+			if prev.Title == "" {
+				prev.Title = doc.Title
+			}
+			if prev.PostedDate.IsZero() {
+				prev.PostedDate = doc.PostedDate
+			}
+			if prev.Type == "" {
+				prev.Type = doc.Type
+			}
+		}
+	}
+	return d
+}
+
+// canonicalSource reduces a result link to scheme, host and path so the same
+// document found through different engines compares equal.
+func canonicalSource(src string) string {
+	s, err := stripQuery(src)
+	if err != nil {
+		return src
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return s
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Host), "www.")
+	return "https://" + host + strings.TrimSuffix(u.EscapedPath(), "/")
+}
+
+func contains(v []string, s string) bool {
+	for _, x := range v {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
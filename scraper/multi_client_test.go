@@ -0,0 +1,41 @@
+package scraper
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+type fakeClient struct {
+	sources []string
+	err     error
+}
+
+func (f fakeClient) Scrape(_ context.Context, r Request) ([]*obj.Document, error) {
+	d := make([]*obj.Document, 0, len(f.sources))
+	for _, src := range f.sources {
+		d = append(d, &obj.Document{Item: obj.NewItem(obj.TDocument), Source: src})
+	}
+	return d, f.err
+}
+
+func TestMultiClient(t *testing.T) {
+	m := &MultiClient{}
+	m.Add(EngineGoogle, fakeClient{sources: []string{"https://www.example.com/a.pdf", "https://example.com/b.pdf"}})
+	m.Add(EngineBing, fakeClient{sources: []string{"https://example.com/a.pdf?utm_source=bing", "https://example.com/c.pdf"}})
+	m.Add(EngineDuckDuckGo, fakeClient{err: errors.New("captcha")})
+
+	d, err := m.Scrape(context.Background(), Request{Symbol: "NVDA", FileType: "pdf"})
+	var engineErrs EngineErrors
+	if !errors.As(err, &engineErrs) || engineErrs[EngineDuckDuckGo] == nil {
+		t.Fatalf("expected duckduckgo error, got %v", err)
+	}
+	if len(d) != 3 {
+		t.Fatalf("expected 3 merged results, got %d", len(d))
+	}
+	if len(d[0].Engines) != 2 || d[0].Engines[1] != EngineBing {
+		t.Fatalf("unexpected engines %v", d[0].Engines)
+	}
+}
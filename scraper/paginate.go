@@ -0,0 +1,108 @@
+package scraper
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+const (
+	DefaultPageDelay = 2 * time.Second
+	DefaultMaxPages  = 10
+)
+
+type resultParser interface {
+	Parse([]byte) ([]*obj.Document, error)
+}
+
+// searchPage fetches a single search engine result page and parses it. It
+// returns a BlockedError if the engine served a block page instead.
+func searchPage(ctx context.Context, engine, uri string, p resultParser) ([]*obj.Document, error) {
+	r, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	setGoogleHeaders(r)
+
+	res, err := http.DefaultClient.Do(r)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	if err = detectBlock(engine, res, b); err != nil {
+		return nil, err
+	}
+	return p.Parse(b)
+}
+
+func (cfg Config) pageDelay() time.Duration {
+	if cfg.PageDelayMs > 0 {
+		return time.Duration(cfg.PageDelayMs) * time.Millisecond
+	}
+	return DefaultPageDelay
+}
+
+// pages returns how many result pages r asks for, at least one and at most
+// the configured maximum.
+func (cfg Config) pages(r Request) int {
+	max := cfg.MaxPages
+	if max <= 0 {
+		max = DefaultMaxPages
+	}
+	switch {
+	case r.Pages <= 0:
+		return 1
+	case r.Pages > max:
+		return max
+	}
+	return r.Pages
+}
+
+// paginate calls fetch for result pages 0, 1, ... until the requested number
+// of pages was read, r.MaxResults results were found, or a page added no new
+// results. It waits the configured page delay between pages. When a page
+// fails, or ctx is done, the results of the earlier pages are returned with
+// the error.
+func (cfg Config) paginate(ctx context.Context, r Request, fetch func(page int) ([]*obj.Document, error)) ([]*obj.Document, error) {
+	d := make([]*obj.Document, 0)
+	seen := make(map[string]bool)
+	pages := cfg.pages(r)
+	for page := 0; page < pages; page++ {
+		if page > 0 {
+			t := time.NewTimer(cfg.pageDelay())
+			select {
+			case <-t.C:
+			case <-ctx.Done():
+				t.Stop()
+				return d, ctx.Err()
+			}
+		}
+		res, err := fetch(page)
+		if err != nil {
+			return d, err
+		}
+		added := 0
+		for _, doc := range res {
+			if doc == nil || seen[doc.Source] {
+				continue
+			}
+			seen[doc.Source] = true
+			d = append(d, doc)
+			added++
+		}
+		if r.MaxResults > 0 && len(d) >= r.MaxResults {
+			return d[:r.MaxResults], nil
+		}
+		if added == 0 {
+			break
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,48 @@
+package scraper
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mrod502/stockscraper/obj"
+)
+
+func TestPaginate(t *testing.T) {
+	cfg := Config{PageDelayMs: 1, MaxPages: 3}
+	fetched := 0
+	fetch := func(page int) ([]*obj.Document, error) {
+		fetched++
+		d := make([]*obj.Document, 0, 10)
+		for i := 0; i < 10; i++ {
+			d = append(d, &obj.Document{Source: fmt.Sprintf("https://example.com/%d.pdf", page*10+i)})
+		}
+		return d, nil
+	}
+
+	d, err := cfg.paginate(context.Background(), Request{Pages: 5}, fetch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fetched != 3 || len(d) != 30 {
+		t.Fatalf("expected 3 pages and 30 results, got %d pages and %d results", fetched, len(d))
+	}
+
+	fetched = 0
+	if d, _ = cfg.paginate(context.Background(), Request{Pages: 3, MaxResults: 15}, fetch); fetched != 2 || len(d) != 15 {
+		t.Fatalf("expected 2 pages and 15 results, got %d pages and %d results", fetched, len(d))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	fetched = 0
+	slow := Config{PageDelayMs: 60000, MaxPages: 3}
+	time.AfterFunc(10*time.Millisecond, cancel)
+	start := time.Now()
+	if d, err = slow.paginate(ctx, Request{Pages: 3}, fetch); err != context.Canceled || fetched != 1 || len(d) != 10 {
+		t.Fatalf("canceled paginate = %d results, %v after %d pages", len(d), err, fetched)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("paginate waited out the page delay")
+	}
+}
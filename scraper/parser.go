@@ -37,14 +37,14 @@ func (p *GParser) Parse(b []byte) (d []*obj.Document, err error) {
 	for {
 		tt := z.Next()
 		if tt == html.ErrorToken {
-			for i, v := range d {
-				if v.Source == "" {
-					if i == (len(d) - 1) {
-						d = d[:i]
-					}
-					d = append(d[:i], d[i+1:]...)
+			// drop results without a link
+			found := d[:0]
+			for _, v := range d {
+				if v.Source != "" {
+					found = append(found, v)
 				}
 			}
+			d = found
 			return
 		}
 		switch tt {
@@ -72,6 +72,9 @@ func (p *GParser) parseSearchResult(t *html.Tokenizer) *obj.Document {
 	for {
 		tt := t.Next()
 		switch tt {
+		case html.ErrorToken:
+			// truncated page, return what we have rather than spin at EOF
+			return doc
 		case html.StartTagToken:
 			level++
 			tk := t.Token()
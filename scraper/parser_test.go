@@ -1,6 +1,7 @@
 package scraper
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"testing"
@@ -44,3 +45,23 @@ func TestParseGoogle(t *testing.T) {
 		fmt.Println(v)
 	}
 }
+
+func TestQueryTemplates(t *testing.T) {
+	cfg := Config{Templates: map[string]string{"deck": "{company} deck {year}"}}
+
+	q, err := cfg.Query(Request{Symbol: "NVDA", FileType: "pdf"})
+	if err != nil || q != "NVDA equity research filetype:pdf" {
+		t.Fatalf("default template rendered %q, %v", q, err)
+	}
+	q, err = cfg.Query(Request{Symbol: "NVDA", FileType: "pdf", Template: "annual report", Year: 2023})
+	if err != nil || q != "NVDA annual report 2023 filetype:pdf" {
+		t.Fatalf("annual report template rendered %q, %v", q, err)
+	}
+	q, err = cfg.Query(Request{Company: "NVIDIA", Template: "deck"})
+	if err != nil || q != "NVIDIA deck" {
+		t.Fatalf("custom template rendered %q, %v", q, err)
+	}
+	if _, err = cfg.Query(Request{Template: "nope"}); !errors.Is(err, ErrUnknownTemplate) {
+		t.Fatalf("expected ErrUnknownTemplate, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package scraper
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultHostRate  = 1.0 // requests per second
+	DefaultHostBurst = 1
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// hostLimiter is a token bucket per host. A host's rate is lowered to match
+// its robots.txt Crawl-delay.
+type hostLimiter struct {
+	l       sync.Mutex
+	buckets map[string]*bucket
+	rate    float64
+	burst   float64
+}
+
+func newHostLimiter(rate float64, burst int) *hostLimiter {
+	if rate <= 0 {
+		rate = DefaultHostRate
+	}
+	if burst <= 0 {
+		burst = DefaultHostBurst
+	}
+	return &hostLimiter{buckets: make(map[string]*bucket), rate: rate, burst: float64(burst)}
+}
+
+// reserve takes a token for host and returns how long the caller has to wait
+// before using it.
+func (h *hostLimiter) reserve(host string, crawlDelay time.Duration) time.Duration {
+	rate := h.rate
+	if crawlDelay > 0 {
+		if r := 1 / crawlDelay.Seconds(); r < rate {
+			rate = r
+		}
+	}
+	now := time.Now()
+
+	h.l.Lock()
+	defer h.l.Unlock()
+	b, ok := h.buckets[host]
+	if !ok {
+		b = &bucket{tokens: h.burst, last: now}
+		h.buckets[host] = b
+	}
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > h.burst {
+		b.tokens = h.burst
+	}
+	b.last = now
+	b.tokens-- // may go negative, later callers queue behind this reservation
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / rate * float64(time.Second))
+}
+
+// wait blocks until a request to host is allowed or ctx is done.
+func (h *hostLimiter) wait(ctx context.Context, host string, crawlDelay time.Duration) error {
+	d := h.reserve(host, crawlDelay)
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,215 @@
+package scraper
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultUserAgent = "stockscraper/1.0 (+https://github.com/mrod502/stockscraper)"
+	robotsTTL        = 24 * time.Hour
+	robotsErrTTL     = 10 * time.Minute
+	maxRobotsSize    = 512 << 10
+)
+
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robots holds the robots.txt rules that apply to our user agent.
+type robots struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+}
+
+var (
+	allowAll    = &robots{}
+	disallowAll = &robots{rules: []robotsRule{{allow: false, pattern: "/"}}}
+)
+
+// parseRobots reads a robots.txt file and keeps the group for agent, falling
+// back to the "*" group. agent is matched case insensitively against the
+// product token of each User-agent line.
+func parseRobots(r io.Reader, agent string) *robots {
+	agent = strings.ToLower(agent)
+	var (
+		specific, wildcard *robots
+		current            []*robots // groups the lines being read belong to
+		inAgents           bool
+	)
+	s := bufio.NewScanner(io.LimitReader(r, maxRobotsSize))
+	for s.Scan() {
+		line := s.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, val, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		val = strings.TrimSpace(val)
+
+		if key == "user-agent" {
+			if !inAgents {
+				current = current[:0]
+				inAgents = true
+			}
+			ua := strings.ToLower(val)
+			switch {
+			case ua == "*":
+				if wildcard == nil {
+					wildcard = &robots{}
+				}
+				current = append(current, wildcard)
+			case ua == agent:
+				if specific == nil {
+					specific = &robots{}
+				}
+				current = append(current, specific)
+			}
+			continue
+		}
+		inAgents = false
+		for _, g := range current {
+			switch key {
+			case "allow", "disallow":
+				if val != "" {
+					g.rules = append(g.rules, robotsRule{allow: key == "allow", pattern: val})
+				}
+			case "crawl-delay":
+				if d, err := strconv.ParseFloat(val, 64); err == nil && d > 0 {
+					g.crawlDelay = time.Duration(d * float64(time.Second))
+				}
+			}
+		}
+	}
+	switch {
+	case specific != nil:
+		return specific
+	case wildcard != nil:
+		return wildcard
+	}
+	return allowAll
+}
+
+// Allowed reports whether path (with query) may be fetched. The longest
+// matching rule wins and allow wins ties.
+func (r *robots) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	allowed, best := true, -1
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		if n := len(rule.pattern); n > best || n == best && rule.allow {
+			allowed, best = rule.allow, n
+		}
+	}
+	return allowed
+}
+
+// robotsMatch matches path against a robots.txt pattern, where * matches any
+// sequence and a trailing $ anchors the end.
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	path = path[len(parts[0]):]
+	for i, part := range parts[1:] {
+		if i == len(parts)-2 && anchored {
+			return strings.HasSuffix(path, part)
+		}
+		j := strings.Index(path, part)
+		if j < 0 {
+			return false
+		}
+		path = path[j+len(part):]
+	}
+	return !anchored || path == ""
+}
+
+type robotsEntry struct {
+	l       sync.Mutex
+	r       *robots
+	expires time.Time
+}
+
+// robotsCache fetches and caches robots.txt per scheme and host.
+type robotsCache struct {
+	l     sync.Mutex
+	hosts map[string]*robotsEntry
+	agent string
+	cli   *http.Client
+}
+
+func newRobotsCache(agent string, cli *http.Client) *robotsCache {
+	return &robotsCache{hosts: make(map[string]*robotsEntry), agent: agent, cli: cli}
+}
+
+// get returns the rules for the host of u, fetching robots.txt if needed.
+func (c *robotsCache) get(ctx context.Context, u *url.URL) *robots {
+	key := u.Scheme + "://" + u.Host
+	c.l.Lock()
+	e, ok := c.hosts[key]
+	if !ok {
+		e = &robotsEntry{}
+		c.hosts[key] = e
+	}
+	c.l.Unlock()
+
+	e.l.Lock()
+	defer e.l.Unlock()
+	if e.r == nil || time.Now().After(e.expires) {
+		e.r, e.expires = c.fetch(ctx, key)
+	}
+	return e.r
+}
+
+// fetch follows RFC 9309: a missing robots.txt allows everything, while an
+// unreachable one disallows everything until it is retried.
+func (c *robotsCache) fetch(ctx context.Context, origin string) (*robots, time.Time) {
+	req, err := http.NewRequestWithContext(ctx, "GET", origin+"/robots.txt", nil)
+	if err != nil {
+		return disallowAll, time.Now().Add(robotsErrTTL)
+	}
+	req.Header.Set("user-agent", c.agent)
+	res, err := c.cli.Do(req)
+	if err != nil && ctx.Err() != nil {
+		// the crawl is stopping, fetch it again next time
+		return disallowAll, time.Time{}
+	}
+	if err != nil {
+		return disallowAll, time.Now().Add(robotsErrTTL)
+	}
+	defer res.Body.Close()
+	switch {
+	case res.StatusCode >= 500:
+		return disallowAll, time.Now().Add(robotsErrTTL)
+	case res.StatusCode >= 400:
+		return allowAll, time.Now().Add(robotsTTL)
+	}
+	return parseRobots(res.Body, agentToken(c.agent)), time.Now().Add(robotsTTL)
+}
+
+// agentToken returns the product token of a user agent string, e.g.
+// "stockscraper" for "stockscraper/1.0 (+https://...)".
+func agentToken(ua string) string {
+	if i := strings.IndexAny(ua, "/ "); i > 0 {
+		return ua[:i]
+	}
+	return ua
+}
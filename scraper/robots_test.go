@@ -0,0 +1,67 @@
+package scraper
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRobots = `
+# comment
+User-agent: *
+Disallow: /private/
+Allow: /private/reports/
+Disallow: /*.php$
+Crawl-delay: 5
+
+User-agent: stockscraper
+User-agent: otherbot
+Disallow: /search
+Allow: /search/about
+Crawl-delay: 2
+`
+
+func TestRobots(t *testing.T) {
+	r := parseRobots(strings.NewReader(testRobots), "stockscraper")
+	if r.crawlDelay != 2*time.Second {
+		t.Fatalf("crawl delay = %s", r.crawlDelay)
+	}
+	for path, want := range map[string]bool{
+		"/search?q=nvda": false,
+		"/search/about":  true,
+		"/private/x":     true, // only the * group forbids it
+	} {
+		if got := r.Allowed(path); got != want {
+			t.Fatalf("Allowed(%s) = %t, want %t", path, got, want)
+		}
+	}
+
+	r = parseRobots(strings.NewReader(testRobots), "somebot")
+	for path, want := range map[string]bool{
+		"/private/x":           false,
+		"/private/reports/a":   true,
+		"/index.php":           false,
+		"/index.php?x=1":       true,
+		"/research/report.pdf": true,
+	} {
+		if got := r.Allowed(path); got != want {
+			t.Fatalf("Allowed(%s) = %t, want %t", path, got, want)
+		}
+	}
+}
+
+func TestHostLimiter(t *testing.T) {
+	h := newHostLimiter(10, 2)
+	if h.reserve("a.com", 0) != 0 || h.reserve("a.com", 0) != 0 {
+		t.Fatal("burst should not wait")
+	}
+	if d := h.reserve("a.com", 0); d <= 0 || d > 100*time.Millisecond {
+		t.Fatalf("third request waited %s", d)
+	}
+	if h.reserve("b.com", 0) != 0 {
+		t.Fatal("hosts should not share buckets")
+	}
+	if d := h.reserve("c.com", time.Second) + h.reserve("c.com", time.Second) + h.reserve("c.com", time.Second); d < 900*time.Millisecond {
+		t.Fatalf("crawl delay not honored, waited %s", d)
+	}
+}
@@ -0,0 +1,143 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// DefaultIgnore is used when CrawlerParams.Ignore is nil. A link is skipped if
+// its URL contains any of these.
+var DefaultIgnore = []string{
+	//set some ignored patterns so we don't get blacklisted :)
+	"googlesyndication",
+	"/video",
+	"viewkey",
+	"youtube.com",
+	"adservices",
+	"adsense",
+	"google",
+	".png",
+	".ico",
+	".js",
+	"reddit.com",
+}
+
+// scope decides which links a crawl follows.
+type scope struct {
+	maxDepth   int
+	sameDomain bool
+	sameHost   bool
+	seedHosts  map[string]bool
+	seedDomain map[string]bool
+	allowHosts []string
+	denyHosts  []string
+	include    []*regexp.Regexp
+	exclude    []*regexp.Regexp
+}
+
+func newScope(params CrawlerParams) (*scope, error) {
+	s := &scope{
+		maxDepth:   params.MaxDepth,
+		sameDomain: params.SameDomain,
+		sameHost:   params.SameHost,
+		seedHosts:  make(map[string]bool),
+		seedDomain: make(map[string]bool),
+		allowHosts: lowerAll(params.AllowHosts),
+		denyHosts:  lowerAll(params.DenyHosts),
+	}
+	for _, seed := range params.Seeds {
+		u, err := url.Parse(seed)
+		if err != nil {
+			return nil, fmt.Errorf("seed %s: %w", seed, err)
+		}
+		host := strings.ToLower(u.Hostname())
+		s.seedHosts[host] = true
+		s.seedDomain[registrableDomain(host)] = true
+	}
+	var err error
+	if s.include, err = compileAll(params.Include); err != nil {
+		return nil, err
+	}
+	if s.exclude, err = compileAll(params.Exclude); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// allowed reports whether a link to u found depth links away from a seed is
+// in scope. u should be canonical.
+func (s *scope) allowed(u *url.URL, depth int) bool {
+	if s.maxDepth > 0 && depth > s.maxDepth {
+		return false
+	}
+	host := u.Hostname()
+	if s.sameHost && !s.seedHosts[host] {
+		return false
+	}
+	if s.sameDomain && !s.seedDomain[registrableDomain(host)] {
+		return false
+	}
+	if len(s.allowHosts) > 0 && !matchHost(host, s.allowHosts) {
+		return false
+	}
+	if matchHost(host, s.denyHosts) {
+		return false
+	}
+	uri := u.String()
+	if len(s.include) > 0 && !matchAny(uri, s.include) {
+		return false
+	}
+	return !matchAny(uri, s.exclude)
+}
+
+// registrableDomain returns the public suffix plus one label of host, e.g.
+// "investor.apple.com" -> "apple.com".
+func registrableDomain(host string) string {
+	if d, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return d
+	}
+	return host
+}
+
+// matchHost reports whether host is one of hosts or a subdomain of one.
+func matchHost(host string, hosts []string) bool {
+	for _, v := range hosts {
+		if host == v || strings.HasSuffix(host, "."+v) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(s string, ptns []*regexp.Regexp) bool {
+	for _, p := range ptns {
+		if p.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+func compileAll(ptns []string) ([]*regexp.Regexp, error) {
+	out := make([]*regexp.Regexp, 0, len(ptns))
+	for _, v := range ptns {
+		p, err := regexp.Compile(v)
+		if err != nil {
+			return nil, fmt.Errorf("url pattern %s: %w", v, err)
+		}
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func lowerAll(in []string) []string {
+	out := make([]string, 0, len(in))
+	for _, v := range in {
+		out = append(out, strings.ToLower(strings.TrimSpace(v)))
+	}
+	return out
+}
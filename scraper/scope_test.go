@@ -0,0 +1,55 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestScope(t *testing.T) {
+	s, err := newScope(CrawlerParams{
+		Seeds:      []string{"https://investor.apple.com/investor-relations/"},
+		MaxDepth:   2,
+		SameDomain: true,
+		DenyHosts:  []string{"jobs.apple.com"},
+		Exclude:    []string{`\.zip$`},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for uri, want := range map[string]bool{
+		"https://investor.apple.com/sec-filings/":  true,
+		"https://www.apple.com/newsroom/":          true,
+		"https://jobs.apple.com/en-us/search":      false,
+		"https://www.sec.gov/cgi-bin/browse-edgar": false,
+		"https://www.apple.com/files/q3.zip":       false,
+	} {
+		u, _ := url.Parse(uri)
+		if got := s.allowed(u, 1); got != want {
+			t.Fatalf("allowed(%s) = %t, want %t", uri, got, want)
+		}
+	}
+	u, _ := url.Parse("https://investor.apple.com/sec-filings/")
+	if s.allowed(u, 3) {
+		t.Fatal("expected depth 3 to be out of scope")
+	}
+
+	s, _ = newScope(CrawlerParams{
+		Seeds:    []string{"https://investor.apple.com/"},
+		SameHost: true,
+		Include:  []string{`/sec-filings/`},
+	})
+	for uri, want := range map[string]bool{
+		"https://investor.apple.com/sec-filings/10k": true,
+		"https://investor.apple.com/events/":         false,
+		"https://www.apple.com/sec-filings/10k":      false,
+	} {
+		u, _ := url.Parse(uri)
+		if got := s.allowed(u, 10); got != want {
+			t.Fatalf("allowed(%s) = %t, want %t", uri, got, want)
+		}
+	}
+
+	if _, err := newScope(CrawlerParams{Exclude: []string{"("}}); err == nil {
+		t.Fatal("expected an error for a bad pattern")
+	}
+}
@@ -0,0 +1,81 @@
+package scraper
+
+import (
+	"net/url"
+	"strings"
+)
+
+var (
+	// trackingParams are query parameters dropped during canonicalization,
+	// in addition to anything starting with "utm_".
+	trackingParams = map[string]bool{
+		"gclid":   true,
+		"fbclid":  true,
+		"msclkid": true,
+		"mc_cid":  true,
+		"mc_eid":  true,
+		"_ga":     true,
+	}
+)
+
+// canonicalURL returns the form of u used to de-duplicate visits: scheme and
+// host lowercased, default ports and the fragment removed, tracking
+// parameters stripped and the remaining query sorted. u is not modified.
+func canonicalURL(u *url.URL) string {
+	c := *u
+	c.Scheme = strings.ToLower(c.Scheme)
+	host := strings.ToLower(c.Hostname())
+	if port := c.Port(); port != "" && !(c.Scheme == "http" && port == "80") && !(c.Scheme == "https" && port == "443") {
+		host += ":" + port
+	}
+	c.Host = host
+	c.User = nil
+	c.Fragment = ""
+	c.RawFragment = ""
+	if c.Path == "" {
+		c.Path = "/"
+		c.RawPath = ""
+	}
+	if c.RawQuery != "" {
+		q := c.Query()
+		for k := range q {
+			if strings.HasPrefix(strings.ToLower(k), "utm_") || trackingParams[strings.ToLower(k)] {
+				q.Del(k)
+			}
+		}
+		c.RawQuery = q.Encode() // Encode sorts by key
+	}
+	c.ForceQuery = false
+	return c.String()
+}
+
+// resolveHref resolves an href found on a page against base and reports
+// whether it points at something we can crawl.
+func resolveHref(base *url.URL, href string) (*url.URL, bool) {
+	href = strings.TrimSpace(href)
+	if href == "" || href[0] == '#' {
+		return nil, false
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return nil, false
+	}
+	u := base.ResolveReference(ref)
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return nil, false
+	}
+	return u, true
+}
+
+// documentBase returns the URL relative links on a page are resolved against:
+// the page's own URL, or its <base href> if it has one.
+func documentBase(page *url.URL, baseHref string) *url.URL {
+	if baseHref == "" {
+		return page
+	}
+	ref, err := url.Parse(baseHref)
+	if err != nil {
+		return page
+	}
+	return page.ResolveReference(ref)
+}
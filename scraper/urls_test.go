@@ -0,0 +1,54 @@
+package scraper
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestResolveHref(t *testing.T) {
+	page, _ := url.Parse("https://example.com/news/2022/article.html?id=4")
+	for href, want := range map[string]string{
+		"../index.html":           "https://example.com/news/index.html",
+		"?page=2":                 "https://example.com/news/2022/article.html?page=2",
+		"other.html":              "https://example.com/news/2022/other.html",
+		"/about":                  "https://example.com/about",
+		"//cdn.example.com/a":     "https://cdn.example.com/a",
+		"http://other.com/x#frag": "http://other.com/x#frag",
+	} {
+		u, ok := resolveHref(page, href)
+		if !ok || u.String() != want {
+			t.Fatalf("resolveHref(%s) = %v, %t, want %s", href, u, ok, want)
+		}
+	}
+	for _, href := range []string{"#top", "mailto:ir@example.com", "javascript:void(0)", ""} {
+		if _, ok := resolveHref(page, href); ok {
+			t.Fatalf("resolveHref(%s) should be skipped", href)
+		}
+	}
+}
+
+func TestDocumentBase(t *testing.T) {
+	page, _ := url.Parse("https://example.com/a/b.html")
+	base := documentBase(page, "/docs/")
+	u, _ := resolveHref(base, "filing.pdf")
+	if u.String() != "https://example.com/docs/filing.pdf" {
+		t.Fatalf("got %s", u)
+	}
+	if documentBase(page, "") != page {
+		t.Fatal("expected page url when there is no base tag")
+	}
+}
+
+func TestCanonicalURL(t *testing.T) {
+	for in, want := range map[string]string{
+		"HTTPS://Example.COM:443/News?b=2&utm_source=x&a=1#section": "https://example.com/News?a=1&b=2",
+		"http://example.com":                   "http://example.com/",
+		"http://example.com:8080/x?fbclid=abc": "http://example.com:8080/x",
+		"https://example.com/x?":               "https://example.com/x",
+	} {
+		u, _ := url.Parse(in)
+		if got := canonicalURL(u); got != want {
+			t.Fatalf("canonicalURL(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package search
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/mrod502/stockscraper/db"
+)
+
+// Index keys. Postings are stored one key per term and document so that adding
+// a document never rewrites the posting lists of other documents.
+const (
+	prefixIndex = "idx:"
+	prefixTerm  = "idx:t:"
+	prefixDoc   = "idx:d:"
+	keyStats    = "idx:stats"
+	defaultK1   = 1.2
+	defaultB    = 0.75
+	maxHitLimit = 1000
+)
+
+var (
+	ErrEmptyQuery = errors.New("empty search query")
+)
+
+// Hit is a single ranked search result.
+type Hit struct {
+	Id    string
+	Score float64
+}
+
+type docEntry struct {
+	Len   uint32
+	Terms []string
+}
+
+type stats struct {
+	Docs   uint64
+	Tokens uint64
+}
+
+// Index is a BM25 ranked inverted index over extracted document text, stored
+// in the same badger database as the documents themselves.
+type Index struct {
+	db *db.DB
+	l  *sync.Mutex
+}
+
+func NewIndex(d *db.DB) *Index {
+	return &Index{db: d, l: &sync.Mutex{}}
+}
+
+func termKey(term, id string) string { return prefixTerm + term + ":" + id }
+
+// Add indexes text under id, replacing any previous entry for id.
+func (i *Index) Add(id string, text string) error {
+	i.l.Lock()
+	defer i.l.Unlock()
+	return i.add(id, text)
+}
+
+// Rebuild clears the index and calls each, which adds every document to the
+// new index with add. It is how text extracted before the index existed, or
+// while it was out of date, gets indexed.
+func (i *Index) Rebuild(each func(add func(id, text string) error) error) error {
+	i.l.Lock()
+	defer i.l.Unlock()
+	if keys := i.db.Keys(prefixIndex); len(keys) > 0 {
+		if err := i.db.DeleteMany(keys); err != nil {
+			return err
+		}
+	}
+	return each(i.add)
+}
+
+func (i *Index) add(id string, text string) error {
+	if err := i.remove(id); err != nil {
+		return err
+	}
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return nil
+	}
+	freq := make(map[string]uint32)
+	for _, t := range tokens {
+		freq[t.term]++
+	}
+	entry := docEntry{Len: uint32(len(tokens)), Terms: make([]string, 0, len(freq))}
+	kv := make(map[string]any, len(freq)+1)
+	for term, n := range freq {
+		entry.Terms = append(entry.Terms, term)
+		kv[termKey(term, id)] = n
+	}
+	kv[prefixDoc+id] = entry
+
+	st, err := i.stats()
+	if err != nil {
+		return err
+	}
+	st.Docs++
+	st.Tokens += uint64(entry.Len)
+	kv[keyStats] = st
+	return i.db.PutMany(kv)
+}
+
+// Remove drops id from the index.
+func (i *Index) Remove(id string) error {
+	i.l.Lock()
+	defer i.l.Unlock()
+	return i.remove(id)
+}
+
+func (i *Index) remove(id string) error {
+	var entry docEntry
+	if err := i.db.Get(prefixDoc+id, &entry); err != nil {
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return nil
+		}
+		return err
+	}
+	keys := make([]string, 0, len(entry.Terms)+1)
+	for _, term := range entry.Terms {
+		keys = append(keys, termKey(term, id))
+	}
+	keys = append(keys, prefixDoc+id)
+	if err := i.db.DeleteMany(keys); err != nil {
+		return err
+	}
+	st, err := i.stats()
+	if err != nil {
+		return err
+	}
+	if st.Docs > 0 {
+		st.Docs--
+	}
+	if st.Tokens >= uint64(entry.Len) {
+		st.Tokens -= uint64(entry.Len)
+	}
+	return i.db.Put(keyStats, st)
+}
+
+func (i *Index) stats() (st stats, err error) {
+	if err = i.db.Get(keyStats, &st); errors.Is(err, badger.ErrKeyNotFound) {
+		err = nil
+	}
+	return
+}
+
+// Search ranks indexed documents against q with BM25 and returns at most
+// limit hits, best first.
+func (i *Index) Search(q string, limit int) ([]Hit, error) {
+	terms := uniq(Terms(q))
+	if len(terms) == 0 {
+		return nil, ErrEmptyQuery
+	}
+	if limit <= 0 || limit > maxHitLimit {
+		limit = maxHitLimit
+	}
+	st, err := i.stats()
+	if err != nil {
+		return nil, err
+	}
+	if st.Docs == 0 {
+		return []Hit{}, nil
+	}
+	avgLen := float64(st.Tokens) / float64(st.Docs)
+	lengths := make(map[string]float64)
+	scores := make(map[string]float64)
+
+	for _, term := range terms {
+		prefix := prefixTerm + term + ":"
+		postings := make(map[string]uint32)
+		err := i.db.Scan(prefix, func(key string, decode func(interface{}) error) error {
+			var tf uint32
+			if err := decode(&tf); err != nil {
+				return err
+			}
+			postings[strings.TrimPrefix(key, prefix)] = tf
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (float64(st.Docs)-df+0.5)/(df+0.5))
+		for id, tf := range postings {
+			dl, ok := lengths[id]
+			if !ok {
+				var entry docEntry
+				if err := i.db.Get(prefixDoc+id, &entry); err != nil {
+					return nil, err
+				}
+				dl = float64(entry.Len)
+				lengths[id] = dl
+			}
+			f := float64(tf)
+			scores[id] += idf * f * (defaultK1 + 1) / (f + defaultK1*(1-defaultB+defaultB*dl/avgLen))
+		}
+	}
+
+	hits := make([]Hit, 0, len(scores))
+	for id, score := range scores {
+		hits = append(hits, Hit{Id: id, Score: score})
+	}
+	sort.Slice(hits, func(a, b int) bool {
+		if hits[a].Score == hits[b].Score {
+			return hits[a].Id < hits[b].Id
+		}
+		return hits[a].Score > hits[b].Score
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+func uniq(v []string) []string {
+	seen := make(map[string]bool, len(v))
+	out := v[:0]
+	for _, s := range v {
+		if !seen[s] {
+			seen[s] = true
+			out = append(out, s)
+		}
+	}
+	return out
+}
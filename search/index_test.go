@@ -0,0 +1,103 @@
+package search
+
+import (
+	"strings"
+	"testing"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/mrod502/stockscraper/db"
+)
+
+func testDB(t *testing.T) *db.DB {
+	d, err := db.New(db.Config{BadgerOpts: badger.DefaultOptions("").WithInMemory(true).WithLogger(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { d.Close() })
+	return d
+}
+
+func TestStem(t *testing.T) {
+	for in, want := range map[string]string{
+		"caresses":    "caress",
+		"ponies":      "poni",
+		"running":     "run",
+		"relational":  "relat",
+		"compression": "compress",
+		"margins":     "margin",
+		"nvda":        "nvda",
+		"2q23":        "2q23",
+	} {
+		if got := stem(in); got != want {
+			t.Fatalf("stem(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	idx := NewIndex(testDB(t))
+
+	docs := map[string]string{
+		"a": "NVDA reported gross margin compression as data center pricing normalized.",
+		"b": "Gross margins expanded at AMD on a richer product mix.",
+		"c": "The weather was nice.",
+	}
+	for id, text := range docs {
+		if err := idx.Add(id, text); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	hits, err := idx.Search("gross margin compression for NVDA", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hits) != 2 || hits[0].Id != "a" {
+		t.Fatalf("unexpected hits %+v", hits)
+	}
+
+	if err = idx.Remove("a"); err != nil {
+		t.Fatal(err)
+	}
+	if hits, _ = idx.Search("nvda", 10); len(hits) != 0 {
+		t.Fatalf("removed document still matches: %+v", hits)
+	}
+	if got := Snippet(docs["b"], "gross margin"); !strings.HasPrefix(got, "Gross margins expanded at AMD") {
+		t.Fatalf("snippet = %q", got)
+	}
+	long := strings.Repeat("Filler text about nothing in particular. ", 20) +
+		"Operating margin compression weighed on results. " + strings.Repeat("More filler follows here. ", 20)
+	got := Snippet(long, "margin compression")
+	if !strings.HasPrefix(got, "…") || !strings.HasSuffix(got, "…") || !strings.Contains(got, "Operating margin compression") {
+		t.Fatalf("snippet = %q", got)
+	}
+}
+
+func TestRebuild(t *testing.T) {
+	idx := NewIndex(testDB(t))
+	if err := idx.Add("stale", "NVDA gross margin"); err != nil {
+		t.Fatal(err)
+	}
+	texts := map[string]string{
+		"a": "NVDA reported gross margin compression.",
+		"b": "AMD guided revenue higher.",
+	}
+	err := idx.Rebuild(func(add func(id, text string) error) error {
+		for id, text := range texts {
+			if err := add(id, text); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	hits, err := idx.Search("nvda margin", 10)
+	if err != nil || len(hits) != 1 || hits[0].Id != "a" {
+		t.Fatalf("hits = %+v, %v", hits, err)
+	}
+	if st, _ := idx.stats(); st.Docs != 2 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
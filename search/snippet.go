@@ -0,0 +1,73 @@
+package search
+
+import (
+	"strings"
+)
+
+const (
+	snippetWindow = 24  // tokens
+	snippetMax    = 280 // bytes
+)
+
+// Snippet returns the passage of text containing the most distinct terms of
+// q, trimmed to a few hundred bytes. It falls back to the start of text when
+// no query term occurs.
+func Snippet(text, q string) string {
+	want := make(map[string]bool)
+	for _, t := range Terms(q) {
+		want[t] = true
+	}
+	tokens := tokenize(text)
+	if len(tokens) == 0 {
+		return ""
+	}
+
+	best, bestScore := 0, 0
+	window := make(map[string]int)
+	distinct := 0
+	for i, t := range tokens {
+		if want[t.term] {
+			if window[t.term] == 0 {
+				distinct++
+			}
+			window[t.term]++
+		}
+		if j := i - snippetWindow; j >= 0 && want[tokens[j].term] {
+			window[tokens[j].term]--
+			if window[tokens[j].term] == 0 {
+				distinct--
+			}
+		}
+		if distinct > bestScore {
+			bestScore = distinct
+			best = i - snippetWindow + 1
+		}
+	}
+	if best < 0 {
+		best = 0
+	}
+	last := best + snippetWindow - 1
+	if last >= len(tokens) {
+		last = len(tokens) - 1
+	}
+
+	start, end := tokens[best].start, tokens[last].end
+	if end-start > snippetMax {
+		end = start + snippetMax
+		for end > start && !isBoundary(text, end) {
+			end--
+		}
+	}
+	s := strings.Join(strings.Fields(text[start:end]), " ")
+	if start > 0 {
+		s = "…" + s
+	}
+	if end < len(text) {
+		s += "…"
+	}
+	return s
+}
+
+func isBoundary(s string, i int) bool {
+	return i >= len(s) || s[i] == ' ' || s[i] == '\n' || s[i] == '\t'
+}
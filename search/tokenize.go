@@ -0,0 +1,64 @@
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+var stopWords = map[string]bool{
+	"a": true, "about": true, "after": true, "all": true, "also": true, "an": true,
+	"and": true, "any": true, "are": true, "as": true, "at": true, "be": true,
+	"been": true, "but": true, "by": true, "can": true, "could": true, "did": true,
+	"do": true, "does": true, "each": true, "for": true, "from": true, "had": true,
+	"has": true, "have": true, "he": true, "her": true, "his": true, "if": true,
+	"in": true, "into": true, "is": true, "it": true, "its": true, "may": true,
+	"more": true, "no": true, "not": true, "of": true, "on": true, "or": true,
+	"other": true, "our": true, "she": true, "should": true, "so": true, "such": true,
+	"than": true, "that": true, "the": true, "their": true, "them": true, "then": true,
+	"there": true, "these": true, "they": true, "this": true, "those": true, "to": true,
+	"under": true, "up": true, "was": true, "we": true, "were": true, "what": true,
+	"when": true, "which": true, "while": true, "who": true, "will": true, "with": true,
+	"would": true, "you": true, "your": true,
+}
+
+type token struct {
+	term       string
+	start, end int // byte offsets of the original word
+}
+
+// tokenize splits s into lowercased, stemmed terms, dropping stop words.
+func tokenize(s string) []token {
+	tokens := make([]token, 0, len(s)/6)
+	start := -1
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		word := strings.ToLower(s[start:end])
+		if !stopWords[word] && len(word) < 64 {
+			tokens = append(tokens, token{term: stem(word), start: start, end: end})
+		}
+		start = -1
+	}
+	for i, r := range s {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(s))
+	return tokens
+}
+
+// Terms returns the index terms for s.
+func Terms(s string) []string {
+	tokens := tokenize(s)
+	terms := make([]string, len(tokens))
+	for i, t := range tokens {
+		terms[i] = t.term
+	}
+	return terms
+}
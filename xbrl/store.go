@@ -0,0 +1,172 @@
+package xbrl
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/mrod502/stockscraper/db"
+)
+
+// Observations are stored one key per symbol, concept, period and set of
+// dimensions, ordered by period end so a series is read with a single scan.
+// A figure reported again by a later filing replaces the earlier one.
+const (
+	prefixObservation = "xbrl:"
+	dateLayout        = "2006-01-02"
+)
+
+// Durations accepted by SeriesQuery.
+const (
+	DurationQuarter = "quarter"
+	DurationYear    = "year"
+	DurationInstant = "instant"
+)
+
+// Observation is the value a filing reported for a concept over a period.
+type Observation struct {
+	Symbol     string
+	Concept    string
+	Value      float64
+	Unit       string `json:",omitempty"`
+	Decimals   string `json:",omitempty" msgpack:",omitempty"`
+	Period     Period
+	Dimensions map[string]string `json:",omitempty" msgpack:",omitempty"`
+	Form       string            `json:",omitempty" msgpack:",omitempty"` // dei:DocumentType of the filing, e.g. "10-Q"
+	Document   string            // id of the filing
+	Filed      time.Time
+}
+
+// SeriesQuery narrows a series. By default it holds every period and only
+// figures for the whole entity.
+type SeriesQuery struct {
+	Duration   string // DurationQuarter, DurationYear, DurationInstant or "" for all
+	Dimensions bool   // include figures for segments, products and other members
+}
+
+// Store keeps the numeric facts of filings queryable by symbol and concept.
+type Store struct {
+	db *db.DB
+	l  *sync.Mutex
+}
+
+func NewStore(d *db.DB) *Store {
+	return &Store{db: d, l: &sync.Mutex{}}
+}
+
+func observationKey(o Observation) string {
+	k := prefixObservation + o.Symbol + ":" + o.Concept + ":" + o.Period.End.Format(dateLayout) + ":"
+	if !o.Period.Instant() {
+		k += o.Period.Start.Format(dateLayout)
+	}
+	if len(o.Dimensions) == 0 {
+		return k
+	}
+	dims := make([]string, 0, len(o.Dimensions))
+	for axis, member := range o.Dimensions {
+		dims = append(dims, axis+"="+member)
+	}
+	sort.Strings(dims)
+	return k + ":" + strings.Join(dims, ",")
+}
+
+// Put stores the numeric facts of a filing under symbol and returns how many
+// were written. A figure already stored from a filing dated after filed is
+// kept, so restatements win whatever order filings are ingested in.
+func (s *Store) Put(symbol, document, form string, filed time.Time, inst *Instance) (int, error) {
+	symbol = strings.ToUpper(symbol)
+	s.l.Lock()
+	defer s.l.Unlock()
+	kv := make(map[string]any)
+	for _, f := range inst.Facts {
+		if !f.Numeric || f.Period.End.IsZero() {
+			continue
+		}
+		o := Observation{
+			Symbol:     symbol,
+			Concept:    f.Concept,
+			Value:      f.Value,
+			Unit:       f.Unit,
+			Decimals:   f.Decimals,
+			Period:     f.Period,
+			Dimensions: f.Dimensions,
+			Form:       form,
+			Document:   document,
+			Filed:      filed,
+		}
+		k := observationKey(o)
+		var prev Observation
+		err := s.db.Get(k, &prev)
+		switch {
+		case err == nil:
+			if prev.Document != document && prev.Filed.After(filed) {
+				continue
+			}
+		case !errors.Is(err, badger.ErrKeyNotFound):
+			return 0, err
+		}
+		kv[k] = o
+	}
+	if len(kv) == 0 {
+		return 0, nil
+	}
+	return len(kv), s.db.PutMany(kv)
+}
+
+// Series returns the stored values of concept for symbol, ordered by the end
+// of their period.
+func (s *Store) Series(symbol, concept string, q SeriesQuery) ([]Observation, error) {
+	out := make([]Observation, 0)
+	prefix := prefixObservation + strings.ToUpper(symbol) + ":" + concept + ":"
+	err := s.db.Scan(prefix, func(key string, decode func(v interface{}) error) error {
+		var o Observation
+		if err := decode(&o); err != nil {
+			return err
+		}
+		if o.Concept != concept || !q.Dimensions && len(o.Dimensions) > 0 || !matchDuration(o.Period, q.Duration) {
+			return nil
+		}
+		out = append(out, o)
+		return nil
+	})
+	return out, err
+}
+
+// matchDuration reports whether p is of the given length. Fiscal quarters
+// and years vary by a few days, so the ranges are loose.
+func matchDuration(p Period, d string) bool {
+	switch d {
+	case DurationQuarter:
+		return p.Days() >= 80 && p.Days() <= 100
+	case DurationYear:
+		return p.Days() >= 350 && p.Days() <= 380
+	case DurationInstant:
+		return p.Instant()
+	}
+	return true
+}
+
+// Concepts returns the concepts stored for symbol, sorted.
+func (s *Store) Concepts(symbol string) []string {
+	prefix := prefixObservation + strings.ToUpper(symbol) + ":"
+	seen := make(map[string]bool)
+	out := make([]string, 0)
+	for _, k := range s.db.Keys(prefix) {
+		parts := strings.Split(strings.TrimPrefix(k, prefix), ":")
+		// the concept runs up to the period end date
+		for i, part := range parts {
+			if _, err := time.Parse(dateLayout, part); err == nil && i > 0 {
+				if c := strings.Join(parts[:i], ":"); !seen[c] {
+					seen[c] = true
+					out = append(out, c)
+				}
+				break
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,60 @@
+package xbrl
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	badger "github.com/dgraph-io/badger/v3"
+	"github.com/mrod502/stockscraper/db"
+)
+
+func TestStoreSeries(t *testing.T) {
+	d, err := db.New(db.Config{BadgerOpts: badger.DefaultOptions("").WithInMemory(true).WithLogger(nil)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+	s := NewStore(d)
+
+	day := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	revenue := func(start, end time.Time, v float64) Fact {
+		return Fact{Concept: "us-gaap:Revenues", Numeric: true, Value: v, Unit: "USD", Period: Period{Start: start, End: end}}
+	}
+	q1 := &Instance{Facts: []Fact{
+		revenue(day(2023, 1, 1), day(2023, 3, 31), 100),
+		{Concept: "dei:TradingSymbol", Text: "ACME"},
+	}}
+	annual := &Instance{Facts: []Fact{
+		revenue(day(2023, 1, 1), day(2023, 3, 31), 110), // restated
+		revenue(day(2023, 4, 1), day(2023, 6, 30), 120),
+		revenue(day(2023, 1, 1), day(2023, 12, 31), 500),
+		{Concept: "us-gaap:Revenues", Numeric: true, Value: 60, Period: Period{Start: day(2023, 4, 1), End: day(2023, 6, 30)},
+			Dimensions: map[string]string{"srt:ProductOrServiceAxis": "acme:WidgetsMember"}},
+		{Concept: "us-gaap:Assets", Numeric: true, Value: 900, Period: Period{End: day(2023, 12, 31)}},
+	}}
+	if n, err := s.Put("acme", "10k", "10-K", day(2024, 2, 1), annual); err != nil || n != 5 {
+		t.Fatalf("put 10-K = %d, %v", n, err)
+	}
+	// the older filing arrives later and must not undo the restatement
+	if n, err := s.Put("ACME", "q1", "10-Q", day(2023, 5, 1), q1); err != nil || n != 0 {
+		t.Fatalf("put 10-Q = %d, %v", n, err)
+	}
+
+	series, err := s.Series("ACME", "us-gaap:Revenues", SeriesQuery{Duration: DurationQuarter})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(series) != 2 || series[0].Value != 110 || series[1].Value != 120 || series[0].Document != "10k" || series[0].Form != "10-K" {
+		t.Fatalf("quarterly series = %+v", series)
+	}
+	if series, _ = s.Series("ACME", "us-gaap:Revenues", SeriesQuery{}); len(series) != 3 || series[2].Value != 500 {
+		t.Fatalf("series = %+v", series)
+	}
+	if series, _ = s.Series("ACME", "us-gaap:Revenues", SeriesQuery{Dimensions: true}); len(series) != 4 {
+		t.Fatalf("series with dimensions = %+v", series)
+	}
+	if got := s.Concepts("ACME"); !reflect.DeepEqual(got, []string{"us-gaap:Assets", "us-gaap:Revenues"}) {
+		t.Fatalf("concepts = %v", got)
+	}
+}
@@ -0,0 +1,399 @@
+package xbrl
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	nsInstance = "http://www.xbrl.org/2003/instance"
+	sniffLen   = 16 << 10
+)
+
+var (
+	ErrNotXBRL = errors.New("no xbrl facts found")
+)
+
+// Period is the time a fact applies to: a day for balance sheet items, a
+// range of days for flows such as revenue. Both are zero for "forever".
+type Period struct {
+	Start time.Time `json:",omitempty"` // zero for an instant
+	End   time.Time // the instant, or the last day of the range
+}
+
+func (p Period) Instant() bool { return p.Start.IsZero() }
+
+// Days returns the length of the period, counting both ends.
+func (p Period) Days() int {
+	if p.Instant() {
+		return 0
+	}
+	return int(p.End.Sub(p.Start).Hours()/24) + 1
+}
+
+// Fact is one tagged value of a filing.
+type Fact struct {
+	Concept    string // prefixed name, e.g. "us-gaap:Revenues"
+	Numeric    bool
+	Value      float64 `json:",omitempty"`
+	Text       string  `json:",omitempty"` // the value of a non-numeric fact
+	Unit       string  `json:",omitempty"` // e.g. "USD" or "USD/shares"
+	Decimals   string  `json:",omitempty"` // precision as tagged, "INF" when exact
+	Entity     string  `json:",omitempty"` // usually the CIK
+	Period     Period
+	Dimensions map[string]string `json:",omitempty"` // axis -> member; none for the whole entity
+}
+
+// Instance is the content of an XBRL instance or inline XBRL document.
+type Instance struct {
+	Facts []Fact
+}
+
+// Text returns the value of the first non-numeric, non-dimensional fact for
+// concept, such as "dei:TradingSymbol", or "".
+func (i *Instance) Text(concept string) string {
+	for _, f := range i.Facts {
+		if !f.Numeric && f.Concept == concept && len(f.Dimensions) == 0 {
+			return strings.TrimSpace(f.Text)
+		}
+	}
+	return ""
+}
+
+// Sniff reports whether b looks like an XBRL instance or an inline XBRL
+// document, judging by the namespaces declared near its start.
+func Sniff(b []byte) bool {
+	if len(b) > sniffLen {
+		b = b[:sniffLen]
+	}
+	return bytes.Contains(b, []byte(nsInstance)) || bytes.Contains(b, []byte("inlineXBRL"))
+}
+
+type contextDef struct {
+	entity string
+	period Period
+	dims   map[string]string
+}
+
+type rawFact struct {
+	concept, contextRef, unitRef, decimals string
+	numeric, inline                        bool
+	scale                                  int
+	negate                                 bool
+	format                                 string
+	text                                   strings.Builder
+	null                                   bool
+}
+
+type parser struct {
+	prefixes map[string]string // namespace -> prefix
+	contexts map[string]*contextDef
+	units    map[string]string
+	facts    []*rawFact
+	open     []*rawFact // facts whose end tag has not been read
+
+	ctx        *contextDef
+	dim        string
+	unitId     string
+	measures   []string
+	num, den   []string
+	unitPart   string
+	text       strings.Builder
+	collecting bool
+}
+
+// Parse reads the facts of an XBRL instance or an inline XBRL (XHTML)
+// document. Facts repeated in several places of an inline document are
+// returned once.
+func Parse(b []byte) (*Instance, error) {
+	p := &parser{
+		prefixes: make(map[string]string),
+		contexts: make(map[string]*contextDef),
+		units:    make(map[string]string),
+	}
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			p.start(t)
+		case xml.EndElement:
+			p.end(t)
+		case xml.CharData:
+			if p.collecting {
+				p.text.Write(t)
+			}
+			for _, f := range p.open {
+				f.text.Write(t)
+			}
+		}
+	}
+	return p.instance()
+}
+
+func isInline(space string) bool {
+	return strings.HasPrefix(space, "http://www.xbrl.org/") && strings.HasSuffix(space, "/inlineXBRL")
+}
+
+func attr(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == name && (a.Name.Space == "" || a.Name.Space == t.Name.Space) {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func (p *parser) start(t xml.StartElement) {
+	for _, a := range t.Attr {
+		if a.Name.Space == "xmlns" {
+			if _, ok := p.prefixes[a.Value]; !ok {
+				p.prefixes[a.Value] = a.Name.Local
+			}
+		}
+	}
+	switch {
+	case t.Name.Space == nsInstance && t.Name.Local == "context":
+		p.ctx = &contextDef{}
+		p.contexts[attr(t, "id")] = p.ctx
+		return
+	case t.Name.Space == nsInstance && t.Name.Local == "unit":
+		p.unitId, p.measures, p.num, p.den = attr(t, "id"), nil, nil, nil
+		return
+	case isInline(t.Name.Space):
+		switch t.Name.Local {
+		case "nonFraction", "nonNumeric":
+			f := &rawFact{
+				concept:    attr(t, "name"),
+				contextRef: attr(t, "contextRef"),
+				unitRef:    attr(t, "unitRef"),
+				decimals:   attr(t, "decimals"),
+				numeric:    t.Name.Local == "nonFraction",
+				inline:     true,
+				negate:     attr(t, "sign") == "-",
+				format:     attr(t, "format"),
+				null:       isNil(t),
+			}
+			f.scale, _ = strconv.Atoi(attr(t, "scale"))
+			p.facts = append(p.facts, f)
+			p.open = append(p.open, f)
+		}
+		return
+	}
+	if p.ctx != nil {
+		switch t.Name.Local {
+		case "identifier", "startDate", "endDate", "instant", "explicitMember", "typedMember":
+			p.dim = attr(t, "dimension")
+			p.text.Reset()
+			p.collecting = true
+		}
+		return
+	}
+	if p.unitId != "" {
+		switch t.Name.Local {
+		case "unitNumerator", "unitDenominator":
+			p.unitPart = t.Name.Local
+		case "measure":
+			p.text.Reset()
+			p.collecting = true
+		}
+		return
+	}
+	if ref := attr(t, "contextRef"); ref != "" {
+		f := &rawFact{
+			concept:    p.concept(t.Name),
+			contextRef: ref,
+			unitRef:    attr(t, "unitRef"),
+			decimals:   attr(t, "decimals"),
+			numeric:    attr(t, "unitRef") != "",
+			null:       isNil(t),
+		}
+		p.facts = append(p.facts, f)
+		p.open = append(p.open, f)
+	}
+}
+
+func (p *parser) end(t xml.EndElement) {
+	switch {
+	case t.Name.Space == nsInstance && t.Name.Local == "context":
+		p.ctx = nil
+		return
+	case t.Name.Space == nsInstance && t.Name.Local == "unit":
+		unit := strings.Join(p.measures, "*")
+		if len(p.num) > 0 {
+			unit = strings.Join(p.num, "*") + "/" + strings.Join(p.den, "*")
+		}
+		p.units[p.unitId] = unit
+		p.unitId = ""
+		return
+	}
+	if p.ctx != nil && p.collecting {
+		text := strings.TrimSpace(p.text.String())
+		switch t.Name.Local {
+		case "identifier":
+			p.ctx.entity = text
+		case "startDate":
+			p.ctx.period.Start = parseDate(text)
+		case "endDate", "instant":
+			p.ctx.period.End = parseDate(text)
+		case "explicitMember", "typedMember":
+			if p.ctx.dims == nil {
+				p.ctx.dims = make(map[string]string)
+			}
+			p.ctx.dims[p.dim] = text
+		default:
+			return
+		}
+		p.collecting = false
+		return
+	}
+	if p.unitId != "" {
+		switch t.Name.Local {
+		case "unitNumerator", "unitDenominator":
+			p.unitPart = ""
+		case "measure":
+			m := measure(p.text.String())
+			switch p.unitPart {
+			case "unitNumerator":
+				p.num = append(p.num, m)
+			case "unitDenominator":
+				p.den = append(p.den, m)
+			default:
+				p.measures = append(p.measures, m)
+			}
+			p.collecting = false
+		}
+		return
+	}
+	if n := len(p.open); n > 0 && (isInline(t.Name.Space) && (t.Name.Local == "nonFraction" || t.Name.Local == "nonNumeric") ||
+		!p.open[n-1].inline && p.open[n-1].concept == p.concept(t.Name)) {
+		p.open = p.open[:n-1]
+	}
+}
+
+// concept returns the prefixed name of a fact element, using the prefix the
+// document declared for its namespace.
+func (p *parser) concept(n xml.Name) string {
+	if prefix, ok := p.prefixes[n.Space]; ok {
+		return prefix + ":" + n.Local
+	}
+	if n.Space != "" && !strings.ContainsAny(n.Space, ":/") {
+		return n.Space + ":" + n.Local // undeclared prefix
+	}
+	return n.Local
+}
+
+func isNil(t xml.StartElement) bool {
+	for _, a := range t.Attr {
+		if a.Name.Local == "nil" && a.Value == "true" {
+			return true
+		}
+	}
+	return false
+}
+
+// measure drops the namespace prefix of a unit measure: "iso4217:USD" is
+// "USD" and "xbrli:shares" is "shares".
+func measure(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexByte(s, ':'); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+func parseDate(s string) time.Time {
+	if len(s) > 10 {
+		s = s[:10]
+	}
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func (p *parser) instance() (*Instance, error) {
+	type key struct{ concept, context, unit string }
+	seen := make(map[key]bool)
+	inst := &Instance{Facts: make([]Fact, 0, len(p.facts))}
+	for _, r := range p.facts {
+		c, ok := p.contexts[r.contextRef]
+		k := key{r.concept, r.contextRef, r.unitRef}
+		if !ok || r.null || r.concept == "" || seen[k] {
+			continue
+		}
+		f := Fact{
+			Concept:    r.concept,
+			Numeric:    r.numeric,
+			Unit:       p.units[r.unitRef],
+			Decimals:   r.decimals,
+			Entity:     c.entity,
+			Period:     c.period,
+			Dimensions: c.dims,
+		}
+		if r.numeric {
+			v, err := number(r.text.String(), r.format)
+			if err != nil {
+				continue
+			}
+			if r.scale != 0 {
+				v *= math.Pow10(r.scale)
+			}
+			if r.negate {
+				v = -v
+			}
+			f.Value = v
+		} else {
+			f.Text = strings.TrimSpace(r.text.String())
+		}
+		seen[k] = true
+		inst.Facts = append(inst.Facts, f)
+	}
+	if len(inst.Facts) == 0 {
+		return nil, ErrNotXBRL
+	}
+	sort.SliceStable(inst.Facts, func(i, j int) bool { return inst.Facts[i].Concept < inst.Facts[j].Concept })
+	return inst, nil
+}
+
+// number parses a numeric fact. Inline values are formatted for display, so
+// grouping separators are dropped according to format, and a dash or a
+// word such as "none" reads as zero.
+func number(s, format string) (float64, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "-", "–", "—", "nil", "no", "none", "zero":
+		return 0, nil
+	}
+	if strings.HasSuffix(format, "fixed-zero") || strings.HasSuffix(format, "fixedzero") {
+		return 0, nil
+	}
+	comma := strings.Contains(format, "comma-decimal") || strings.Contains(format, "numcommadecimal")
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == ',' && comma, r == '.' && !comma:
+			b.WriteByte('.')
+		case r == '-' && b.Len() == 0:
+			b.WriteRune(r)
+		}
+	}
+	return strconv.ParseFloat(b.String(), 64)
+}
@@ -0,0 +1,149 @@
+package xbrl
+
+import (
+	"testing"
+	"time"
+)
+
+const testInstance = `<?xml version="1.0" encoding="utf-8"?>
+<xbrli:xbrl xmlns:xbrli="http://www.xbrl.org/2003/instance" xmlns:us-gaap="http://fasb.org/us-gaap/2023"
+  xmlns:dei="http://xbrl.sec.gov/dei/2023" xmlns:iso4217="http://www.xbrl.org/2003/iso4217"
+  xmlns:xbrldi="http://xbrl.org/2006/xbrldi" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance">
+  <xbrli:context id="q4">
+    <xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">0000320193</xbrli:identifier></xbrli:entity>
+    <xbrli:period><xbrli:startDate>2023-07-02</xbrli:startDate><xbrli:endDate>2023-09-30</xbrli:endDate></xbrli:period>
+  </xbrli:context>
+  <xbrli:context id="q4-americas">
+    <xbrli:entity>
+      <xbrli:identifier scheme="http://www.sec.gov/CIK">0000320193</xbrli:identifier>
+      <xbrli:segment><xbrldi:explicitMember dimension="us-gaap:StatementBusinessSegmentsAxis">aapl:AmericasSegmentMember</xbrldi:explicitMember></xbrli:segment>
+    </xbrli:entity>
+    <xbrli:period><xbrli:startDate>2023-07-02</xbrli:startDate><xbrli:endDate>2023-09-30</xbrli:endDate></xbrli:period>
+  </xbrli:context>
+  <xbrli:context id="end">
+    <xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">0000320193</xbrli:identifier></xbrli:entity>
+    <xbrli:period><xbrli:instant>2023-09-30</xbrli:instant></xbrli:period>
+  </xbrli:context>
+  <xbrli:unit id="usd"><xbrli:measure>iso4217:USD</xbrli:measure></xbrli:unit>
+  <xbrli:unit id="usdPerShare">
+    <xbrli:divide>
+      <xbrli:unitNumerator><xbrli:measure>iso4217:USD</xbrli:measure></xbrli:unitNumerator>
+      <xbrli:unitDenominator><xbrli:measure>xbrli:shares</xbrli:measure></xbrli:unitDenominator>
+    </xbrli:divide>
+  </xbrli:unit>
+  <dei:TradingSymbol contextRef="q4">AAPL</dei:TradingSymbol>
+  <us-gaap:Revenues contextRef="q4" unitRef="usd" decimals="-6">89498000000</us-gaap:Revenues>
+  <us-gaap:Revenues contextRef="q4-americas" unitRef="usd" decimals="-6">40115000000</us-gaap:Revenues>
+  <us-gaap:EarningsPerShareDiluted contextRef="q4" unitRef="usdPerShare" decimals="2">1.46</us-gaap:EarningsPerShareDiluted>
+  <us-gaap:Cash contextRef="end" unitRef="usd" decimals="-6">29965000000</us-gaap:Cash>
+  <us-gaap:Goodwill contextRef="end" unitRef="usd" xsi:nil="true"/>
+</xbrli:xbrl>`
+
+const testInline = `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:ix="http://www.xbrl.org/2013/inlineXBRL"
+  xmlns:xbrli="http://www.xbrl.org/2003/instance" xmlns:us-gaap="http://fasb.org/us-gaap/2023"
+  xmlns:dei="http://xbrl.sec.gov/dei/2023" xmlns:ixt="http://www.xbrl.org/inlineXBRL/transformation/2020-02-12">
+<head><title>10-Q</title></head>
+<body>
+<div style="display:none"><ix:header><ix:resources>
+  <xbrli:context id="c-1">
+    <xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">0001045810</xbrli:identifier></xbrli:entity>
+    <xbrli:period><xbrli:startDate>2023-04-01</xbrli:startDate><xbrli:endDate>2023-06-30</xbrli:endDate></xbrli:period>
+  </xbrli:context>
+  <xbrli:unit id="usd"><xbrli:measure>iso4217:USD</xbrli:measure></xbrli:unit>
+</ix:resources></ix:header></div>
+<p>Ticker: <ix:nonNumeric name="dei:TradingSymbol" contextRef="c-1">NVDA</ix:nonNumeric>&nbsp;</p>
+<table>
+<tr><td>Revenue</td><td>$<ix:nonFraction name="us-gaap:Revenues" contextRef="c-1" unitRef="usd" decimals="-6" scale="6" format="ixt:num-dot-decimal">13,507</ix:nonFraction></td></tr>
+<tr><td>Other income (expense)</td><td>(<ix:nonFraction name="us-gaap:OtherNonoperatingIncomeExpense" contextRef="c-1" unitRef="usd" decimals="-6" scale="6" sign="-">1<span>8</span></ix:nonFraction>)</td></tr>
+<tr><td>Impairment</td><td><ix:nonFraction name="us-gaap:GoodwillImpairmentLoss" contextRef="c-1" unitRef="usd" scale="6" format="ixt:fixed-zero">—</ix:nonFraction></td></tr>
+</table>
+<p>Revenue was $<ix:nonFraction name="us-gaap:Revenues" contextRef="c-1" unitRef="usd" decimals="-8" scale="9">13.5</ix:nonFraction> billion.</p>
+</body></html>`
+
+func find(t *testing.T, inst *Instance, concept string, dims bool) Fact {
+	t.Helper()
+	for _, f := range inst.Facts {
+		if f.Concept == concept && (len(f.Dimensions) > 0) == dims {
+			return f
+		}
+	}
+	t.Fatalf("%s not found in %+v", concept, inst.Facts)
+	return Fact{}
+}
+
+func TestParseInstance(t *testing.T) {
+	if !Sniff([]byte(testInstance)) {
+		t.Fatal("instance not sniffed")
+	}
+	inst, err := Parse([]byte(testInstance))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inst.Facts) != 5 {
+		t.Fatalf("facts = %+v", inst.Facts)
+	}
+	if s := inst.Text("dei:TradingSymbol"); s != "AAPL" {
+		t.Fatalf("symbol = %q", s)
+	}
+	rev := find(t, inst, "us-gaap:Revenues", false)
+	if rev.Value != 89498000000 || rev.Unit != "USD" || rev.Decimals != "-6" || rev.Entity != "0000320193" ||
+		!rev.Period.Start.Equal(time.Date(2023, 7, 2, 0, 0, 0, 0, time.UTC)) || rev.Period.Days() != 91 {
+		t.Fatalf("revenues = %+v", rev)
+	}
+	seg := find(t, inst, "us-gaap:Revenues", true)
+	if seg.Value != 40115000000 || seg.Dimensions["us-gaap:StatementBusinessSegmentsAxis"] != "aapl:AmericasSegmentMember" {
+		t.Fatalf("segment revenues = %+v", seg)
+	}
+	if eps := find(t, inst, "us-gaap:EarningsPerShareDiluted", false); eps.Value != 1.46 || eps.Unit != "USD/shares" {
+		t.Fatalf("eps = %+v", eps)
+	}
+	if cash := find(t, inst, "us-gaap:Cash", false); !cash.Period.Instant() || cash.Period.End.Day() != 30 {
+		t.Fatalf("cash = %+v", cash)
+	}
+}
+
+func TestParseInline(t *testing.T) {
+	if !Sniff([]byte(testInline)) {
+		t.Fatal("inline document not sniffed")
+	}
+	inst, err := Parse([]byte(testInline))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := inst.Text("dei:TradingSymbol"); s != "NVDA" {
+		t.Fatalf("symbol = %q", s)
+	}
+	wants := map[string]float64{
+		"us-gaap:Revenues":                       13507e6,
+		"us-gaap:OtherNonoperatingIncomeExpense": -18e6,
+		"us-gaap:GoodwillImpairmentLoss":         0,
+	}
+	for concept, v := range wants {
+		if f := find(t, inst, concept, false); f.Value != v || f.Unit != "USD" || f.Period.Days() != 91 {
+			t.Errorf("%s = %+v", concept, f)
+		}
+	}
+	if len(inst.Facts) != 4 {
+		t.Fatalf("repeated fact not dropped: %+v", inst.Facts)
+	}
+}
+
+func TestNumber(t *testing.T) {
+	for _, c := range []struct {
+		s, format string
+		want      float64
+	}{
+		{"1,234.5", "ixt:num-dot-decimal", 1234.5},
+		{"1.234,5", "ixt:num-comma-decimal", 1234.5},
+		{"1 234", "", 1234},
+		{"-12.5", "", -12.5},
+		{"none", "ixt-sec:numwordsen", 0},
+	} {
+		if v, err := number(c.s, c.format); err != nil || v != c.want {
+			t.Errorf("number(%q, %q) = %v, %v", c.s, c.format, v, err)
+		}
+	}
+	if Sniff([]byte("<html><body>10-K</body></html>")) {
+		t.Fatal("plain html sniffed as xbrl")
+	}
+}